@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"slices"
-	"time"
 
 	"github.com/0mjs/zinc"
 	z "github.com/0mjs/zinc"
@@ -156,7 +155,8 @@ func main() {
 
 	// App-level middleware
 	// Note: App-level middleware is used to apply middleware to all routes, regardless of the route group.
-	app.Use(MyMiddleware())
+	// Logger records every request with log/slog.
+	app.Use(z.Logger(z.LoggerConfig{SkipPaths: []string{"/health"}}))
 
 	// Route-level middleware
 	// Note: Route-level middleware is used to apply middleware to a specific route.
@@ -252,7 +252,7 @@ func CustomHTML(c *zinc.Context) {
 
 func MyMiddleware() zinc.Middleware {
 	return func(c *zinc.Context) {
-		fmt.Println("Request received:", c.Request.URL.Path, "Method:", c.Request.Method, "Time:", time.Now().Format(time.RFC3339))
+		c.Logger().Info("request received")
 		c.Next()
 	}
 }
@@ -0,0 +1,298 @@
+package zinc
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Binding sources, matching the struct tags read by Context.Bind.
+const (
+	BindPath   = "path"
+	BindQuery  = "query"
+	BindHeader = "header"
+	BindForm   = "form"
+)
+
+// defaultMultipartMemory is the memory limit used when parsing multipart bodies.
+const defaultMultipartMemory = 32 << 20
+
+var (
+	ErrBindTarget           = errors.New("bind target must be a non-nil pointer to a struct")
+	ErrUnsupportedMediaType = NewHTTPError(http.StatusUnsupportedMediaType)
+)
+
+// BindError describes a value that could not be bound to a struct field.
+type BindError struct {
+	Field  string `json:"field"`
+	Source string `json:"source"`
+	Value  string `json:"value"`
+	Err    error  `json:"-"`
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("%s %q: cannot bind %q: %v", e.Source, e.Field, e.Value, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// BindErrors collects every field that failed to bind.
+type BindErrors []*BindError
+
+func (e BindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// StatusCode returns 400 Bad Request.
+func (e BindErrors) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// bindField describes a tagged struct field.
+type bindField struct {
+	index  []int
+	name   string
+	source string
+	key    string
+	format string
+}
+
+// Cache of tagged fields per struct type
+var bindCache sync.Map
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// Bind populates the struct pointed to by v from the request.
+// The body is decoded according to its Content-Type using the registered
+// decoders or as a form,
+// then fields tagged with `path`, `query`, `header` or `form` are filled
+// from the matching request values. Time fields accept a `format` tag.
+// Conversion failures are returned together as BindErrors; the bound
+// value is then checked with the configured Validator.
+func (c *Context) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrBindTarget
+	}
+
+	if err := c.bindBody(v); err != nil {
+		return err
+	}
+
+	var errs BindErrors
+	for _, f := range cachedBindFields(rv.Elem().Type()) {
+		values, ok := c.bindValues(f.source, f.key)
+		if !ok {
+			continue
+		}
+		if err := setField(rv.Elem().FieldByIndex(f.index), values, f.format); err != nil {
+			errs = append(errs, &BindError{
+				Field:  f.name,
+				Source: f.source,
+				Value:  strings.Join(values, ","),
+				Err:    err,
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return c.Validate(v)
+}
+
+// bindBody decodes the request body according to its Content-Type.
+func (c *Context) bindBody(v interface{}) error {
+	r := c.Request
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+
+	switch c.mediaType() {
+	case "application/x-www-form-urlencoded":
+		return r.ParseForm()
+	case "multipart/form-data":
+		_, err := c.MultipartForm()
+		return err
+	default:
+		defer r.Body.Close()
+		return c.decodeBody(v)
+	}
+}
+
+// bindValues returns the raw request values for a binding source and key.
+func (c *Context) bindValues(source, key string) ([]string, bool) {
+	switch source {
+	case BindPath:
+		v, ok := c.PathParams[key]
+		return []string{v}, ok
+	case BindQuery:
+		v, ok := c.QueryParams[key]
+		return v, ok
+	case BindHeader:
+		v, ok := c.Request.Header[http.CanonicalHeaderKey(key)]
+		return v, ok
+	case BindForm:
+		v, ok := c.Request.PostForm[key]
+		return v, ok
+	}
+	return nil, false
+}
+
+// cachedBindFields returns the tagged fields of a struct type.
+func cachedBindFields(t reflect.Type) []bindField {
+	if fields, ok := bindCache.Load(t); ok {
+		return fields.([]bindField)
+	}
+	fields := collectBindFields(t, nil)
+	bindCache.Store(t, fields)
+	return fields
+}
+
+func collectBindFields(t reflect.Type, index []int) []bindField {
+	var fields []bindField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectBindFields(sf.Type, idx)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		for _, source := range []string{BindPath, BindQuery, BindHeader, BindForm} {
+			key, ok := sf.Tag.Lookup(source)
+			if !ok || key == "-" {
+				continue
+			}
+			key, _, _ = strings.Cut(key, ",")
+			if key == "" {
+				key = sf.Name
+			}
+			fields = append(fields, bindField{
+				index:  idx,
+				name:   sf.Name,
+				source: source,
+				key:    key,
+				format: sf.Tag.Get("format"),
+			})
+		}
+	}
+	return fields
+}
+
+// setField converts the raw values and assigns them to the field.
+func setField(field reflect.Value, values []string, format string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setField(field.Elem(), values, format)
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 &&
+		!reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value, format); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return setValue(field, values[0], format)
+}
+
+// setValue converts a single raw value and assigns it.
+func setValue(v reflect.Value, raw string, format string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), raw, format)
+	}
+
+	if v.Type() == timeType && format != "" {
+		t, err := time.Parse(format, raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		v.SetBytes([]byte(raw))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
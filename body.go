@@ -0,0 +1,139 @@
+package zinc
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrBodyTooLarge is returned when a request body exceeds its limit,
+// before or after decompression.
+var ErrBodyTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge)
+
+// maxZstdWindow bounds the memory a zstd body can make the decoder allocate.
+const maxZstdWindow = 8 << 20
+
+// BodyLimit returns middleware that replaces Config.BodyLimit for the routes
+// or group it is applied to. A negative limit removes the limit.
+// It must run before the body is read:
+//
+//	app.Post("/upload", zinc.BodyLimit(64<<20), upload)
+func BodyLimit(limit int64) Middleware {
+	return func(c *Context) {
+		c.bodyLimit = limit
+	}
+}
+
+// requestBody limits and decompresses a request body. It is set up on the
+// first read, so that a BodyLimit applied after the application middleware
+// takes effect.
+type requestBody struct {
+	c        *Context
+	body     io.ReadCloser
+	length   int64
+	encoding string
+	r        io.Reader
+	dec      io.Closer
+	err      error
+}
+
+// wrapBody replaces the request body with one enforcing the body limit and
+// decoding its Content-Encoding, which is removed from the request headers.
+func (c *Context) wrapBody() {
+	r := c.Request
+	if r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+	b := &requestBody{c: c, body: r.Body, length: r.ContentLength}
+	if enc := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); enc != "" && enc != "identity" {
+		b.encoding = enc
+		r.Header.Del("Content-Encoding")
+		r.ContentLength = -1
+	}
+	r.Body = b
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.err = b.open()
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = b.readError(err)
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *requestBody) open() error {
+	limit := b.c.bodyLimit
+	if limit > 0 && b.length > limit {
+		return ErrBodyTooLarge
+	}
+
+	var r io.ReadCloser = b.body
+	if limit > 0 {
+		r = http.MaxBytesReader(b.c.Response, r, limit)
+	}
+
+	var dec io.ReadCloser
+	var err error
+	switch b.encoding {
+	case "":
+		b.r = r
+		return nil
+	case "gzip", "x-gzip":
+		dec, err = gzip.NewReader(r)
+	case "deflate":
+		dec, err = zlib.NewReader(r)
+	case "zstd":
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(maxZstdWindow)); err == nil {
+			dec = zr.IOReadCloser()
+		}
+	default:
+		return NewHTTPError(http.StatusUnsupportedMediaType, "unsupported Content-Encoding "+b.encoding)
+	}
+	if err != nil {
+		return b.readError(err)
+	}
+
+	// Limit the decompressed size as well, so that a small body cannot
+	// expand without bound.
+	b.dec = dec
+	b.r = dec
+	if limit > 0 {
+		b.r = http.MaxBytesReader(b.c.Response, dec, limit)
+	}
+	return nil
+}
+
+// readError reports bodies over the limit as ErrBodyTooLarge
+// and undecodable compressed bodies as 400 Bad Request.
+func (b *requestBody) readError(err error) error {
+	var mbe *http.MaxBytesError
+	switch {
+	case errors.As(err, &mbe), errors.Is(err, zstd.ErrWindowSizeExceeded):
+		return ErrBodyTooLarge
+	case b.encoding != "":
+		return &HTTPError{Code: http.StatusBadRequest, Message: "malformed " + b.encoding + " body", Err: err}
+	}
+	return err
+}
+
+func (b *requestBody) Close() error {
+	if b.dec != nil {
+		b.dec.Close()
+		b.dec = nil
+	}
+	return b.body.Close()
+}
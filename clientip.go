@@ -0,0 +1,191 @@
+package zinc
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// forwardedHop is a client or proxy in the forwarding headers of a request.
+type forwardedHop struct {
+	addr  netip.Addr
+	proto string
+	host  string
+}
+
+// ClientIP returns the IP address of the client. Forwarding headers are only
+// used when the request comes from a proxy in Config.TrustedProxies: the
+// addresses in the Forwarded header, or else X-Forwarded-For, are read from
+// right to left and the first one that is not a trusted proxy is the client,
+// so that addresses added by the client itself are ignored. X-Real-IP is used
+// when neither header is present.
+func (c *Context) ClientIP() string {
+	hop, _ := c.clientHop()
+	if !hop.addr.IsValid() {
+		return remoteIP(c.Request.RemoteAddr)
+	}
+	return hop.addr.String()
+}
+
+// Scheme returns "https" or "http": the protocol of the client's request
+// as reported by a trusted proxy in the Forwarded or X-Forwarded-Proto
+// header, or otherwise the protocol of the connection.
+func (c *Context) Scheme() string {
+	if hop, trusted := c.clientHop(); trusted {
+		proto := hop.proto
+		if proto == "" {
+			proto = lastHeaderValue(c.Request.Header, "X-Forwarded-Proto")
+		}
+		if proto = strings.ToLower(proto); proto == "https" || proto == "http" {
+			return proto
+		}
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host requested by the client, as reported by a trusted
+// proxy in the Forwarded or X-Forwarded-Host header, or otherwise the Host
+// header of the request.
+func (c *Context) Host() string {
+	if hop, trusted := c.clientHop(); trusted {
+		host := hop.host
+		if host == "" {
+			host = lastHeaderValue(c.Request.Header, "X-Forwarded-Host")
+		}
+		if host != "" {
+			return host
+		}
+	}
+	return c.Request.Host
+}
+
+// clientHop returns the hop of the client, reporting whether the request
+// came through a trusted proxy.
+func (c *Context) clientHop() (forwardedHop, bool) {
+	remote, err := netip.ParseAddr(remoteIP(c.Request.RemoteAddr))
+	if err != nil || !c.trustedProxy(remote.Unmap()) {
+		return forwardedHop{addr: remote.Unmap()}, false
+	}
+	remoteHop := forwardedHop{addr: remote.Unmap()}
+
+	header := c.Request.Header
+	hops := parseForwarded(header.Values("Forwarded"))
+	if len(hops) == 0 {
+		hops = parseForwardedFor(header.Values("X-Forwarded-For"))
+	}
+	if len(hops) == 0 {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(header.Get("X-Real-IP"))); err == nil {
+			return forwardedHop{addr: addr.Unmap()}, true
+		}
+		return remoteHop, true
+	}
+
+	// Walk from the nearest proxy towards the client. An address that cannot
+	// be parsed ends the walk at the last hop known to be trustworthy.
+	next := remoteHop
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if !hop.addr.IsValid() {
+			return next, true
+		}
+		if i == 0 || !c.trustedProxy(hop.addr) {
+			return hop, true
+		}
+		next = hop
+	}
+	return next, true
+}
+
+func (c *Context) trustedProxy(addr netip.Addr) bool {
+	if c.app == nil {
+		return false
+	}
+	for _, p := range c.app.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses IP addresses and CIDR ranges.
+func parseTrustedProxies(proxies []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, s := range proxies {
+		if strings.Contains(s, "/") {
+			prefixes = append(prefixes, netip.MustParsePrefix(s).Masked())
+			continue
+		}
+		addr := netip.MustParseAddr(s).Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes
+}
+
+// parseForwarded parses the elements of RFC 7239 Forwarded headers.
+// Elements whose "for" is missing, obfuscated or "unknown" have an invalid address.
+func parseForwarded(values []string) []forwardedHop {
+	var hops []forwardedHop
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			if strings.TrimSpace(element) == "" {
+				continue
+			}
+			var hop forwardedHop
+			for _, pair := range strings.Split(element, ";") {
+				name, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				v = strings.Trim(v, `"`)
+				switch strings.ToLower(name) {
+				case "for":
+					hop.addr = parseNodeAddr(v)
+				case "proto":
+					hop.proto = v
+				case "host":
+					hop.host = v
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseForwardedFor parses the addresses of X-Forwarded-For headers.
+func parseForwardedFor(values []string) []forwardedHop {
+	var hops []forwardedHop
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				hops = append(hops, forwardedHop{addr: parseNodeAddr(s)})
+			}
+		}
+	}
+	return hops
+}
+
+// parseNodeAddr parses an address that may have a port
+// and, for IPv6, brackets.
+func parseNodeAddr(s string) netip.Addr {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap()
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// lastHeaderValue returns the last comma-separated value of a header,
+// which is the one added by the nearest proxy.
+func lastHeaderValue(header http.Header, name string) string {
+	values := header.Values(name)
+	if len(values) == 0 {
+		return ""
+	}
+	parts := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
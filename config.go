@@ -4,10 +4,88 @@ package zinc
 type Config struct {
 	// DefaultAddr specifies the HTTP server address.
 	DefaultAddr string
+
+	// Validator validates values decoded by Context.Body and Context.Bind.
+	// Defaults to DefaultValidator.
+	Validator Validator
+
+	// ErrorHandler responds to errors passed to Context.Error.
+	// Defaults to DefaultErrorHandler.
+	ErrorHandler ErrorHandler
+
+	// JSONCodec encodes responses and decodes request bodies.
+	// Defaults to StdJSONCodec.
+	JSONCodec JSONCodec
+
+	// JSONIndent indents encoded JSON when set, e.g. "  ".
+	JSONIndent string
+
+	// JSONDisableHTMLEscape stops <, > and & being escaped in encoded strings.
+	JSONDisableHTMLEscape bool
+
+	// JSONDisallowUnknownFields rejects request bodies with fields
+	// that do not exist in the destination struct.
+	JSONDisallowUnknownFields bool
+
+	// JSONUseNumber decodes numbers into interface{} values as json.Number.
+	JSONUseNumber bool
+
+	// CookieKeys sign and encrypt cookies. New cookies use the first key;
+	// the others are still accepted so that keys can be rotated.
+	// Keys should be at least 32 random bytes.
+	CookieKeys [][]byte
+
+	// FileRoot restricts the files served by Context.File, Attachment and
+	// Inline to a directory. Paths are resolved inside it, and paths that
+	// escape it, including through symbolic links, are refused.
+	FileRoot string
+
+	// Uploads limits the multipart bodies read by Context.MultipartForm,
+	// FormFile, FormValue and Uploads.
+	Uploads UploadLimits
+
+	// BodyLimit limits the size of request bodies, both as received and after
+	// decompression; larger bodies are refused with 413. Defaults to 4MB, and
+	// a negative value removes the limit. The BodyLimit middleware replaces it
+	// for a route or group, such as one accepting large uploads.
+	BodyLimit int64
+
+	// TrustedProxies lists the IP addresses and CIDR ranges, such as
+	// "10.0.0.0/8", of the proxies trusted to report the client's address,
+	// protocol and host in forwarding headers. See Context.ClientIP.
+	TrustedProxies []string
 }
 
 // DefaultConfig provides the default server configuration.
 // It can be used as a base configuration for the server initialisation.
 var DefaultConfig = Config{
 	DefaultAddr: "0.0.0.0:8080",
+	Validator:   DefaultValidator,
+	JSONCodec:   StdJSONCodec,
+	BodyLimit:   4 << 20,
+}
+
+// DefaultErrorHandler refers to DefaultConfig through Context.JSON,
+// so it is set here rather than in the initialiser.
+func init() {
+	DefaultConfig.ErrorHandler = DefaultErrorHandler
+}
+
+// setDefaults fills unset fields from DefaultConfig.
+func (c *Config) setDefaults() {
+	if c.DefaultAddr == "" {
+		c.DefaultAddr = DefaultConfig.DefaultAddr
+	}
+	if c.Validator == nil {
+		c.Validator = DefaultConfig.Validator
+	}
+	if c.ErrorHandler == nil {
+		c.ErrorHandler = DefaultConfig.ErrorHandler
+	}
+	if c.JSONCodec == nil {
+		c.JSONCodec = DefaultConfig.JSONCodec
+	}
+	if c.BodyLimit == 0 {
+		c.BodyLimit = DefaultConfig.BodyLimit
+	}
 }
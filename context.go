@@ -1,15 +1,25 @@
 package zinc
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"log/slog"
+	"maps"
+	"mime"
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Context holds the context for a request.
 // It is used to pass data between middleware and handlers.
+//
+// Context implements context.Context, so it can be passed to functions that
+// take one. Contexts are reused once the request completes, unless they have
+// been used as a context.Context; use Copy to pass one to a goroutine that
+// may outlive the handler.
 type Context struct {
 	Response    http.ResponseWriter
 	Request     *http.Request
@@ -22,6 +32,18 @@ type Context struct {
 	Store       map[string]interface{}
 	status      int
 	services    map[string]interface{}
+	app         *App
+	writer      ResponseWriter
+	bodyLimit   int64
+	released    atomic.Bool
+	escaped     atomic.Bool
+	scope       *scope
+	route       string
+	logConfig   *LoggerConfig
+	requestID   string
+	cleanups    []func()
+	formErr     error
+	logger      *slog.Logger // built by Logger, cleared when its attributes change
 }
 
 // Pool of contexts to reduce allocations
@@ -43,13 +65,22 @@ func NewContext(w http.ResponseWriter, r *http.Request) *Context {
 
 // Add method to reset context state
 func (c *Context) reset(w http.ResponseWriter, r *http.Request) {
-	c.Response = w
+	c.writer.reset(w)
+	c.Response = &c.writer
 	c.Request = r
 	c.QueryParams = r.URL.Query()
 	c.Method = r.Method
 	c.written = false
 	c.index = -1
-	c.status = http.StatusOK
+	c.status = 0
+	c.released.Store(false)
+	c.escaped.Store(false)
+	c.scope = nil
+	c.route = ""
+	c.logConfig = nil
+	c.requestID = ""
+	c.formErr = nil
+	c.logger = nil
 
 	// Clear maps instead of reallocating
 	for k := range c.PathParams {
@@ -60,16 +91,42 @@ func (c *Context) reset(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// onRelease registers fn to run when the request completes, before the
+// Context is reused.
+func (c *Context) onRelease(fn func()) {
+	c.cleanups = append(c.cleanups, fn)
+}
+
 // Add method to release context back to pool
 func (c *Context) release() {
+	for _, fn := range c.cleanups {
+		fn()
+	}
+	c.cleanups = nil
+	if c.Request != nil && c.Request.MultipartForm != nil {
+		c.Request.MultipartForm.RemoveAll()
+	}
+	if err := c.closeScope(); err != nil {
+		c.Logger().Error("closing request services", "error", err)
+	}
+	// A Context used as a context.Context may still be read by the code it
+	// was passed to, so it is left intact and not reused.
+	if c.escaped.Load() {
+		c.released.Store(true)
+		return
+	}
+	c.scope = nil
 	c.Response = nil
+	c.writer.reset(nil)
 	c.Request = nil
+	c.app = nil
 	c.handlers = nil
 	c.QueryParams = nil
+	c.released.Store(true)
 	contextPool.Put(c)
 }
 
-// Service returns a service by name.
+// Service returns a service by name. It panics if the service is not registered.
 func (c *Context) Service(name string) interface{} {
 	if service, exists := c.services[name]; exists {
 		return service
@@ -77,14 +134,60 @@ func (c *Context) Service(name string) interface{} {
 	panic("Service '" + name + "' not found")
 }
 
-// Next calls the next middleware in the chain.
+// LookupService returns a service by name, reporting whether it is registered.
+func (c *Context) LookupService(name string) (interface{}, bool) {
+	service, exists := c.services[name]
+	return service, exists
+}
+
+// Next calls the remaining handlers in the chain.
+// Middleware that calls Next runs around the handlers after it and can inspect
+// the response once Next returns. Middleware that does not call Next is followed
+// by the next handler after it returns. The chain stops once a response is written.
 func (c *Context) Next() {
 	c.index++
-	if c.index < len(c.handlers) {
+	for c.index < len(c.handlers) {
 		c.handlers[c.index](c)
+		if c.Written() {
+			return
+		}
+		c.index++
+	}
+}
+
+// Written reports whether a response has been sent, either by a render
+// method or by writing to Response directly.
+func (c *Context) Written() bool {
+	return c.written || c.writer.Written()
+}
+
+// ResponseStatus returns the status code sent to the client.
+// Before the response is written it returns the status set with Status,
+// or 200 if none was set. A status set but not written by the route
+// handlers is sent once they return, so middleware sees the final status.
+func (c *Context) ResponseStatus() int {
+	if c.writer.Written() {
+		return c.writer.Status()
+	}
+	if c.status != 0 {
+		return c.status
+	}
+	return http.StatusOK
+}
+
+// writeStatus sends a status set with Status when nothing was written,
+// so that the client receives it rather than 200.
+func (c *Context) writeStatus() {
+	if c.status != 0 && !c.Written() {
+		c.Response.WriteHeader(c.status)
 	}
 }
 
+// ResponseSize returns the number of body bytes written.
+func (c *Context) ResponseSize() int64 {
+	return c.writer.Size()
+}
+
 // setHandlers sets the handlers for the context.
 func (c *Context) setHandlers(handlers []Middleware) {
 	c.handlers = handlers
@@ -93,14 +196,109 @@ func (c *Context) setHandlers(handlers []Middleware) {
 
 // Set stores a value in the context store.
 func (c *Context) Set(key string, value interface{}) {
+	c.checkReleased()
 	c.Store[key] = value
 }
 
 // Get retrieves a value from the context store.
 func (c *Context) Get(key string) interface{} {
+	c.checkReleased()
 	return c.Store[key]
 }
 
+// Deadline returns the deadline of the request context.
+func (c *Context) Deadline() (time.Time, bool) {
+	c.escape()
+	return c.Request.Context().Deadline()
+}
+
+// Done returns a channel closed when the request is cancelled,
+// the client disconnects or a deadline set with WithTimeout passes.
+func (c *Context) Done() <-chan struct{} {
+	c.escape()
+	return c.Request.Context().Done()
+}
+
+// Err reports why Done was closed.
+func (c *Context) Err() error {
+	c.escape()
+	return c.Request.Context().Err()
+}
+
+// Value returns the value stored with Set for a string key,
+// or else the value of the request context for key.
+func (c *Context) Value(key interface{}) interface{} {
+	c.escape()
+	if k, ok := key.(string); ok {
+		if v, ok := c.Store[k]; ok {
+			return v
+		}
+	}
+	return c.Request.Context().Value(key)
+}
+
+// WithTimeout sets a deadline on the request context, after which Done is
+// closed. The returned function releases its resources and should be deferred.
+func (c *Context) WithTimeout(d time.Duration) context.CancelFunc {
+	c.checkReleased()
+	ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+	c.Request = c.Request.WithContext(ctx)
+	return cancel
+}
+
+// Copy returns a copy of the Context that remains usable after the request
+// completes. Its context keeps the request's values but is not cancelled with
+// it, and it cannot send a response: render methods return ErrResponseAlreadySent.
+// Scoped services cannot be resolved through it.
+func (c *Context) Copy() *Context {
+	c.checkReleased()
+	cp := &Context{
+		Request:     c.Request.WithContext(context.WithoutCancel(c.Request.Context())),
+		PathParams:  maps.Clone(c.PathParams),
+		QueryParams: maps.Clone(c.QueryParams),
+		Method:      c.Method,
+		Store:       maps.Clone(c.Store),
+		status:      c.status,
+		services:    c.services,
+		app:         c.app,
+		bodyLimit:   c.bodyLimit,
+		written:     true,
+		scope:       &scope{closed: true},
+		route:       c.route,
+		logConfig:   c.logConfig,
+		requestID:   c.requestID,
+	}
+	cp.writer.reset(&detachedResponse{header: c.Response.Header().Clone()})
+	cp.Response = &cp.writer
+	return cp
+}
+
+// escape marks the Context as used as a context.Context,
+// which may be kept after the request completes.
+func (c *Context) escape() {
+	if !c.escaped.Load() {
+		c.checkReleased()
+		c.escaped.Store(true)
+	}
+}
+
+// checkReleased panics if the Context is used after its request completed,
+// which is detected until the Context is reused for another request.
+func (c *Context) checkReleased() {
+	if c.released.Load() {
+		panic("zinc: Context used after its request completed; use Context.Copy in goroutines")
+	}
+}
+
+// detachedResponse is the response of a copied Context.
+type detachedResponse struct {
+	header http.Header
+}
+
+func (r *detachedResponse) Header() http.Header        { return r.header }
+func (r *detachedResponse) Write([]byte) (int, error)  { return 0, ErrResponseAlreadySent }
+func (r *detachedResponse) WriteHeader(statusCode int) {}
+
 // Status sets the status code for the response.
 func (c *Context) Status(code int) *Context {
 	c.status = code
@@ -109,20 +307,58 @@ func (c *Context) Status(code int) *Context {
 
 // Param retrieves a path parameter by name.
 func (c *Context) Param(name string) string {
+	c.checkReleased()
 	return c.PathParams[name]
 }
 
 // Query retrieves a query parameter by name.
 func (c *Context) Query(name string) string {
+	c.checkReleased()
 	return c.QueryParams.Get(name)
 }
 
-// Body decodes the request body into the provided interface.
+// Body decodes the request body into the provided interface and validates it.
+// The decoder is chosen by the Content-Type header, defaulting to JSON.
 func (c *Context) Body(v interface{}) error {
 	if c.Request.Body == nil {
 		return errors.New("request body is nil")
 	}
 	defer c.Request.Body.Close()
 
-	return json.NewDecoder(c.Request.Body).Decode(v)
+	if err := c.decodeBody(v); err != nil {
+		return err
+	}
+	return c.Validate(v)
+}
+
+// decodeBody decodes the request body with the decoder registered for its media type.
+func (c *Context) decodeBody(v interface{}) error {
+	dec, ok := c.codecs().decoder(c.mediaType())
+	if !ok {
+		return ErrUnsupportedMediaType
+	}
+	return dec.Decode(c.Request.Body, v)
+}
+
+// mediaType returns the media type of the request body, defaulting to JSON.
+func (c *Context) mediaType() string {
+	mediaType, _, err := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		return "application/json"
+	}
+	return mediaType
+}
+
+// Validate validates v with the configured Validator.
+func (c *Context) Validate(v interface{}) error {
+	return c.config().Validator.Validate(v)
+}
+
+// config returns the application configuration, or DefaultConfig
+// for contexts created outside of an App.
+func (c *Context) config() *Config {
+	if c.app == nil {
+		return &DefaultConfig
+	}
+	return c.app.config
 }
@@ -0,0 +1,243 @@
+package zinc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCookie is returned when a signed or encrypted cookie fails verification.
+	ErrInvalidCookie = errors.New("invalid cookie")
+	// ErrNoCookieKeys is returned when signing or encrypting without Config.CookieKeys.
+	ErrNoCookieKeys = errors.New("no cookie keys configured")
+)
+
+// Cookie describes a cookie set with Context.SetCookie. Unlike http.Cookie,
+// its zero value is secure: HttpOnly is on unless ScriptAccess is set,
+// SameSite defaults to Lax (http.SameSiteDefaultMode omits it), the path
+// defaults to "/" and Secure is set for requests made over HTTPS.
+type Cookie struct {
+	Name        string
+	Value       string
+	Path        string
+	Domain      string
+	MaxAge      int
+	Expires     time.Time
+	SameSite    http.SameSite
+	Secure      bool
+	Partitioned bool
+
+	// ScriptAccess leaves HttpOnly off so client scripts can read the cookie.
+	ScriptAccess bool
+}
+
+// Cookie returns the value of the named request cookie, or "" if it is not set.
+func (c *Context) Cookie(name string) string {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// SetCookie adds a Set-Cookie header to the response.
+func (c *Context) SetCookie(cookie Cookie) {
+	hc := &http.Cookie{
+		Name:        cookie.Name,
+		Value:       cookie.Value,
+		Path:        cookie.Path,
+		Domain:      cookie.Domain,
+		MaxAge:      cookie.MaxAge,
+		Expires:     cookie.Expires,
+		SameSite:    cookie.SameSite,
+		Secure:      cookie.Secure || c.Scheme() == "https",
+		HttpOnly:    !cookie.ScriptAccess,
+		Partitioned: cookie.Partitioned,
+	}
+	if hc.Path == "" {
+		hc.Path = "/"
+	}
+	if hc.SameSite == 0 {
+		hc.SameSite = http.SameSiteLaxMode
+	}
+	if hc.SameSite == http.SameSiteNoneMode {
+		// Browsers reject SameSite=None cookies that are not Secure.
+		hc.Secure = true
+	}
+	http.SetCookie(c.Response, hc)
+}
+
+// ClearCookie tells the client to delete the named cookie at path "/".
+// Cookies set with another path or domain are cleared with SetCookie and a negative MaxAge.
+func (c *Context) ClearCookie(name string) {
+	c.SetCookie(Cookie{Name: name, MaxAge: -1, Expires: time.Unix(0, 0)})
+}
+
+// SetSignedCookie sets a cookie whose value is signed with HMAC-SHA256.
+// The value is readable by the client but cannot be modified.
+func (c *Context) SetSignedCookie(cookie Cookie) error {
+	keys := c.cookieKeys()
+	if len(keys) == 0 {
+		return ErrNoCookieKeys
+	}
+	cookie.Value = keys[0].sign(cookie.Name, cookie.Value)
+	c.SetCookie(cookie)
+	return nil
+}
+
+// SignedCookie returns the value of a cookie set with SetSignedCookie.
+// It returns http.ErrNoCookie if the cookie is missing and ErrInvalidCookie
+// if the signature does not match any of the configured keys.
+func (c *Context) SignedCookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	keys := c.cookieKeys()
+	if len(keys) == 0 {
+		return "", ErrNoCookieKeys
+	}
+	for _, key := range keys {
+		if value, ok := key.verify(name, cookie.Value); ok {
+			return value, nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// SetEncryptedCookie sets a cookie whose value is encrypted with AES-GCM.
+// The value can neither be read nor modified by the client.
+func (c *Context) SetEncryptedCookie(cookie Cookie) error {
+	keys := c.cookieKeys()
+	if len(keys) == 0 {
+		return ErrNoCookieKeys
+	}
+	value, err := keys[0].encrypt(cookie.Name, cookie.Value)
+	if err != nil {
+		return err
+	}
+	cookie.Value = value
+	c.SetCookie(cookie)
+	return nil
+}
+
+// EncryptedCookie returns the value of a cookie set with SetEncryptedCookie.
+// It returns http.ErrNoCookie if the cookie is missing and ErrInvalidCookie
+// if it cannot be decrypted with any of the configured keys.
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	keys := c.cookieKeys()
+	if len(keys) == 0 {
+		return "", ErrNoCookieKeys
+	}
+	for _, key := range keys {
+		if value, ok := key.decrypt(name, cookie.Value); ok {
+			return value, nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// cookieKeys returns the keys derived from Config.CookieKeys.
+func (c *Context) cookieKeys() []cookieKey {
+	if c.app == nil {
+		return deriveCookieKeys(DefaultConfig.CookieKeys)
+	}
+	return c.app.cookieKeys
+}
+
+// cookieKey holds the signing and encryption keys derived from one secret,
+// so that a single secret is never used for both purposes.
+type cookieKey struct {
+	macKey []byte
+	aead   cipher.AEAD
+}
+
+func deriveCookieKeys(secrets [][]byte) []cookieKey {
+	keys := make([]cookieKey, 0, len(secrets))
+	for _, secret := range secrets {
+		block, err := aes.NewCipher(deriveKey(secret, "zinc cookie encryption"))
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		keys = append(keys, cookieKey{macKey: deriveKey(secret, "zinc cookie signing"), aead: aead})
+	}
+	return keys
+}
+
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+var cookieEncoding = base64.RawURLEncoding
+
+// sign encodes the value and appends a MAC over the cookie name and value,
+// so that a signed value cannot be moved to another cookie.
+func (k cookieKey) sign(name, value string) string {
+	payload := cookieEncoding.EncodeToString([]byte(value))
+	return payload + "." + cookieEncoding.EncodeToString(k.mac(name, payload))
+}
+
+func (k cookieKey) verify(name, signed string) (string, bool) {
+	payload, sig, ok := strings.Cut(signed, ".")
+	if !ok {
+		return "", false
+	}
+	mac, err := cookieEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, k.mac(name, payload)) {
+		return "", false
+	}
+	value, err := cookieEncoding.DecodeString(payload)
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+func (k cookieKey) mac(name, payload string) []byte {
+	mac := hmac.New(sha256.New, k.macKey)
+	mac.Write([]byte(name))
+	mac.Write([]byte{'='})
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// encrypt seals the value with a random nonce, authenticating the cookie name.
+func (k cookieKey) encrypt(name, value string) (string, error) {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(value)+k.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := k.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return cookieEncoding.EncodeToString(sealed), nil
+}
+
+func (k cookieKey) decrypt(name, encrypted string) (string, bool) {
+	sealed, err := cookieEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < k.aead.NonceSize() {
+		return "", false
+	}
+	nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	value, err := k.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
@@ -0,0 +1,195 @@
+package zinc
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowOrigins lists the origins allowed to make cross-origin requests:
+	// exact origins such as "https://app.example.com", origins with a wildcard
+	// subdomain such as "https://*.example.com", or "*" for any origin.
+	// Defaults to "*" when no other way of allowing origins is set.
+	AllowOrigins []string
+
+	// AllowOriginPatterns lists regular expressions that allowed origins
+	// match in full. It panics on invalid expressions.
+	AllowOriginPatterns []string
+
+	// AllowOriginFunc reports whether an origin is allowed.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods lists the methods allowed in preflight responses.
+	// Defaults to the methods routed for the requested path.
+	AllowMethods []string
+
+	// AllowHeaders lists the request headers allowed in preflight responses.
+	// Defaults to the headers the preflight request asks for.
+	AllowHeaders []string
+
+	// ExposeHeaders lists the response headers that scripts may read.
+	ExposeHeaders []string
+
+	// AllowCredentials allows requests with cookies and HTTP authentication.
+	// Origins allowed by "*" are then echoed, as browsers reject "*".
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache preflight responses.
+	MaxAge time.Duration
+
+	// AllowPrivateNetwork allows public websites to make requests to a
+	// server on a private network, when the browser asks for it.
+	AllowPrivateNetwork bool
+}
+
+// CORS returns middleware that applies cross-origin resource sharing.
+// Preflight requests are answered with 204 No Content, listing the methods
+// routed for the path, so CORS must be installed with App.Use in order to see
+// them. Responses that depend on the Origin header carry Vary: Origin.
+func CORS(config ...CORSConfig) Middleware {
+	var cfg CORSConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if len(cfg.AllowOrigins) == 0 && len(cfg.AllowOriginPatterns) == 0 && cfg.AllowOriginFunc == nil {
+		cfg.AllowOrigins = []string{"*"}
+	}
+	patterns := make([]*regexp.Regexp, len(cfg.AllowOriginPatterns))
+	for i, p := range cfg.AllowOriginPatterns {
+		patterns[i] = regexp.MustCompile("^(?:" + p + ")$")
+	}
+	allowAll := slices.Contains(cfg.AllowOrigins, "*")
+	// Responses to any origin are the same unless origins are echoed.
+	varies := !allowAll || cfg.AllowCredentials
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		for _, o := range cfg.AllowOrigins {
+			if matchOrigin(o, origin) {
+				return true
+			}
+		}
+		for _, p := range patterns {
+			if p.MatchString(origin) {
+				return true
+			}
+		}
+		return cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(origin)
+	}
+
+	return func(c *Context) {
+		header := c.Response.Header()
+		if varies {
+			header.Add("Vary", "Origin")
+		}
+		origin := c.Request.Header.Get("Origin")
+		if origin == "" {
+			return
+		}
+
+		preflight := c.Request.Method == MethodOptions && c.Request.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if !allowed(origin) {
+			if preflight {
+				c.Status(http.StatusNoContent)
+				c.Response.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+
+		if allowAll && !cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			return
+		}
+
+		methods := cfg.AllowMethods
+		if methods == nil && c.app != nil {
+			methods = c.app.router.allowedMethods(c.Request.URL.Path)
+			if len(methods) == 0 {
+				// Leave unknown paths to the router.
+				return
+			}
+		}
+		header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.Request.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if cfg.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge/time.Second)))
+		}
+		if cfg.AllowPrivateNetwork && c.Request.Header.Get("Access-Control-Request-Private-Network") == "true" {
+			header.Add("Vary", "Access-Control-Request-Private-Network")
+			header.Set("Access-Control-Allow-Private-Network", "true")
+		}
+		c.Status(http.StatusNoContent)
+		c.Response.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// matchOrigin reports whether origin matches an allowed origin,
+// which may have a "*" in place of its subdomains.
+func matchOrigin(allowed, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(allowed, "*")
+	if !wildcard {
+		return strings.EqualFold(allowed, origin)
+	}
+	if len(origin) <= len(prefix)+len(suffix) ||
+		!strings.EqualFold(origin[:len(prefix)], prefix) || !strings.EqualFold(origin[len(origin)-len(suffix):], suffix) {
+		return false
+	}
+	sub := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(sub, "/:@")
+}
+
+// allowedMethods returns the methods routed for a path, sorted.
+// HEAD and OPTIONS are not included.
+func (r *Router) allowedMethods(path string) []string {
+	if r.router == nil {
+		return nil
+	}
+	pattern := ""
+	parts := getPathParts(path)
+	if node := r.router.find(parts, make(map[string]string)); node != nil && node.handler != nil {
+		pattern = node.path
+	}
+	pathPartsCache.Put(parts)
+
+	var methods []string
+	for method, routes := range r.routes {
+		if method == MethodHead || method == MethodOptions {
+			continue
+		}
+		if len(routes[path]) > 0 || pattern != "" && len(routes[pattern]) > 0 {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
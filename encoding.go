@@ -0,0 +1,487 @@
+package zinc
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotAcceptable is returned by Context.Negotiate when no registered
+// encoder satisfies the Accept header.
+var ErrNotAcceptable = NewHTTPError(http.StatusNotAcceptable)
+
+// Encoder writes a value in a particular media type.
+type Encoder interface {
+	Encode(w io.Writer, v interface{}) error
+}
+
+// Decoder reads a value from a particular media type.
+type Decoder interface {
+	Decode(r io.Reader, v interface{}) error
+}
+
+// SelectiveEncoder is implemented by encoders that only handle some values,
+// such as CSV for slices of structs. Negotiation skips encoders whose
+// Supports method returns false.
+type SelectiveEncoder interface {
+	Encoder
+	Supports(v interface{}) bool
+}
+
+// EncoderFunc adapts a function to the Encoder interface.
+type EncoderFunc func(w io.Writer, v interface{}) error
+
+// Encode calls f(w, v).
+func (f EncoderFunc) Encode(w io.Writer, v interface{}) error {
+	return f(w, v)
+}
+
+// DecoderFunc adapts a function to the Decoder interface.
+type DecoderFunc func(r io.Reader, v interface{}) error
+
+// Decode calls f(r, v).
+func (f DecoderFunc) Decode(r io.Reader, v interface{}) error {
+	return f(r, v)
+}
+
+type encoderEntry struct {
+	mediaType   string
+	contentType string
+	encoder     Encoder
+}
+
+// codecRegistry holds the encoders and decoders of an App.
+// Encoders are kept in registration order, which breaks ties during negotiation.
+type codecRegistry struct {
+	encoders []encoderEntry
+	decoders map[string]Decoder
+}
+
+// RegisterEncoder registers an encoder for a content type such as
+// "application/json; charset=utf-8", replacing any encoder for the same media type.
+func (a *App) RegisterEncoder(contentType string, enc Encoder) {
+	a.codecs.registerEncoder(contentType, enc)
+}
+
+// RegisterDecoder registers a decoder used by Context.Body and Context.Bind
+// for requests with the given media type.
+func (a *App) RegisterDecoder(mediaType string, dec Decoder) {
+	a.codecs.decoders[strings.ToLower(mediaType)] = dec
+}
+
+func (r *codecRegistry) registerEncoder(contentType string, enc Encoder) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		panic("zinc: invalid content type '" + contentType + "'")
+	}
+	entry := encoderEntry{mediaType: mediaType, contentType: contentType, encoder: enc}
+	for i, e := range r.encoders {
+		if e.mediaType == mediaType {
+			r.encoders[i] = entry
+			return
+		}
+	}
+	r.encoders = append(r.encoders, entry)
+}
+
+// decoder returns the decoder for a media type.
+func (r *codecRegistry) decoder(mediaType string) (Decoder, bool) {
+	dec, ok := r.decoders[mediaType]
+	if !ok && strings.HasSuffix(mediaType, "+json") {
+		dec, ok = r.decoders["application/json"]
+	}
+	return dec, ok
+}
+
+var (
+	msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+	cborHandle    = &codec.CborHandle{}
+)
+
+func init() {
+	mapType := reflect.TypeOf(map[string]interface{}(nil))
+	msgpackHandle.MapType = mapType
+	cborHandle.MapType = mapType
+}
+
+// newCodecRegistry returns the built-in encoders and decoders:
+// JSON, XML, YAML, MessagePack, CBOR, CSV and protobuf.
+// JSON uses the codec and options of the configuration.
+func newCodecRegistry(cfg *Config) *codecRegistry {
+	r := &codecRegistry{decoders: map[string]Decoder{}}
+
+	jsonCodec := EncoderFunc(cfg.encodeJSON)
+	xmlCodec := xmlEncoder{}
+	yamlCodec := EncoderFunc(func(w io.Writer, v interface{}) error {
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+	msgpackCodec := EncoderFunc(func(w io.Writer, v interface{}) error {
+		return codec.NewEncoder(w, msgpackHandle).Encode(v)
+	})
+	cborCodec := EncoderFunc(func(w io.Writer, v interface{}) error {
+		return codec.NewEncoder(w, cborHandle).Encode(v)
+	})
+
+	r.registerEncoder("application/json; charset=utf-8", jsonCodec)
+	r.registerEncoder("application/xml; charset=utf-8", xmlCodec)
+	r.registerEncoder("text/xml; charset=utf-8", xmlCodec)
+	r.registerEncoder("application/yaml; charset=utf-8", yamlCodec)
+	r.registerEncoder("application/x-yaml; charset=utf-8", yamlCodec)
+	r.registerEncoder("text/yaml; charset=utf-8", yamlCodec)
+	r.registerEncoder("application/msgpack", msgpackCodec)
+	r.registerEncoder("application/x-msgpack", msgpackCodec)
+	r.registerEncoder("application/cbor", cborCodec)
+	r.registerEncoder("text/csv; charset=utf-8", csvCodec{})
+	r.registerEncoder("application/protobuf", protoCodec{})
+	r.registerEncoder("application/x-protobuf", protoCodec{})
+
+	decodeWith := func(h codec.Handle) DecoderFunc {
+		return func(rd io.Reader, v interface{}) error {
+			return codec.NewDecoder(rd, h).Decode(v)
+		}
+	}
+	for mediaType, dec := range map[string]Decoder{
+		"application/json": DecoderFunc(cfg.decodeJSON),
+		"application/xml":  xmlCodec,
+		"text/xml":         xmlCodec,
+		"application/yaml": DecoderFunc(func(rd io.Reader, v interface{}) error {
+			return yaml.NewDecoder(rd).Decode(v)
+		}),
+		"application/msgpack":    decodeWith(msgpackHandle),
+		"application/cbor":       decodeWith(cborHandle),
+		"text/csv":               csvCodec{},
+		"application/protobuf":   protoCodec{},
+		"application/x-protobuf": protoCodec{},
+	} {
+		r.decoders[mediaType] = dec
+	}
+	r.decoders["application/x-yaml"] = r.decoders["application/yaml"]
+	r.decoders["text/yaml"] = r.decoders["application/yaml"]
+	r.decoders["application/x-msgpack"] = r.decoders["application/msgpack"]
+
+	return r
+}
+
+// codecs returns the codec registry of the application.
+func (c *Context) codecs() *codecRegistry {
+	if c.app == nil {
+		return defaultCodecs
+	}
+	return c.app.codecs
+}
+
+// Registry used by contexts created outside of an App
+var defaultCodecs = newCodecRegistry(&DefaultConfig)
+
+// Negotiate sends data in the format that best matches the Accept header,
+// honouring q-values and preferring encoders in registration order.
+// A request without an Accept header receives the first registered encoder
+// that supports the value (JSON by default). When nothing fits, a 406 is sent
+// through the ErrorHandler and ErrNotAcceptable is returned.
+func (c *Context) Negotiate(data interface{}) error {
+	if c.Written() {
+		return ErrResponseAlreadySent
+	}
+
+	entry, ok := c.codecs().negotiate(c.Request.Header.Get("Accept"), data)
+	if !ok {
+		c.Error(ErrNotAcceptable)
+		return ErrNotAcceptable
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := entry.encoder.Encode(buf, data); err != nil {
+		return err
+	}
+
+	c.Response.Header().Add("Vary", "Accept")
+	return c.writeBuffer(entry.contentType, buf)
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate selects the encoder with the highest quality for the Accept header.
+func (r *codecRegistry) negotiate(accept string, data interface{}) (encoderEntry, bool) {
+	ranges := parseAccept(accept)
+
+	best, bestQ := -1, 0.0
+	for i, e := range r.encoders {
+		if se, ok := e.encoder.(SelectiveEncoder); ok && !se.Supports(data) {
+			continue
+		}
+		q := 1.0
+		if len(ranges) > 0 {
+			q = mediaQuality(ranges, e.mediaType)
+		}
+		if q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+
+	if best < 0 {
+		return encoderEntry{}, false
+	}
+	return r.encoders[best], true
+}
+
+// parseAccept parses an Accept header, ordering ranges from most to least specific.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+
+	specificity := func(mediaType string) int {
+		switch {
+		case mediaType == "*/*":
+			return 0
+		case strings.HasSuffix(mediaType, "/*"):
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+	return ranges
+}
+
+// mediaQuality returns the q-value of the most specific range matching the media type.
+func mediaQuality(ranges []acceptRange, mediaType string) float64 {
+	for _, rng := range ranges {
+		if rng.mediaType == mediaType || rng.mediaType == "*/*" ||
+			strings.HasSuffix(rng.mediaType, "/*") && strings.HasPrefix(mediaType, rng.mediaType[:len(rng.mediaType)-1]) {
+			return rng.q
+		}
+	}
+	return 0
+}
+
+// xmlEncoder encodes with encoding/xml, which cannot represent maps.
+type xmlEncoder struct{}
+
+func (xmlEncoder) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlEncoder) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+func (xmlEncoder) Supports(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() != reflect.Map
+}
+
+// protoCodec encodes protobuf messages.
+type protoCodec struct{}
+
+func (protoCodec) Encode(w io.Writer, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (protoCodec) Decode(r io.Reader, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
+
+func (protoCodec) Supports(v interface{}) bool {
+	_, ok := v.(proto.Message)
+	return ok
+}
+
+// csvCodec encodes slices of structs as CSV with a header row.
+// Columns are named by the `csv` tag, falling back to the JSON field name.
+type csvCodec struct{}
+
+// csvColumns returns the column names and field indexes of a struct type.
+func csvColumns(t reflect.Type) ([]string, []int) {
+	var names []string
+	var indexes []int
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("csv"), ",")
+		if name == "" {
+			name = fieldName(sf)
+		}
+		if name == "-" || sf.Tag.Get("json") == "-" {
+			continue
+		}
+		names = append(names, name)
+		indexes = append(indexes, i)
+	}
+	return names, indexes
+}
+
+// csvElem returns the struct type of a slice of structs or struct pointers.
+func csvElem(t reflect.Type) (reflect.Type, bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) {
+		return nil, false
+	}
+	elem := t.Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	return elem, elem.Kind() == reflect.Struct
+}
+
+func (csvCodec) Supports(v interface{}) bool {
+	_, ok := csvElem(reflect.TypeOf(v))
+	return ok
+}
+
+func (csvCodec) Encode(w io.Writer, v interface{}) error {
+	elem, ok := csvElem(reflect.TypeOf(v))
+	if !ok {
+		return fmt.Errorf("csv: cannot encode %T", v)
+	}
+	names, indexes := csvColumns(elem)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(names); err != nil {
+		return err
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	record := make([]string, len(indexes))
+	for i := 0; i < rv.Len(); i++ {
+		row := reflect.Indirect(rv.Index(i))
+		for j, idx := range indexes {
+			record[j] = ""
+			if row.IsValid() {
+				record[j] = csvFormat(row.Field(idx))
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvFormat(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Decode reads CSV with a header row into a pointer to a slice of structs.
+func (csvCodec) Decode(r io.Reader, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("csv: decode target must be a pointer to a slice of structs")
+	}
+	elem, ok := csvElem(rv.Elem().Type())
+	if !ok {
+		return errors.New("csv: decode target must be a pointer to a slice of structs")
+	}
+
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil || len(records) == 0 {
+		return err
+	}
+
+	names, indexes := csvColumns(elem)
+	columns := make([]int, len(records[0]))
+	for i, header := range records[0] {
+		columns[i] = -1
+		for j, name := range names {
+			if name == header {
+				columns[i] = indexes[j]
+			}
+		}
+	}
+
+	slice := rv.Elem()
+	for line, record := range records[1:] {
+		row := reflect.New(elem).Elem()
+		for i, value := range record {
+			if i >= len(columns) || columns[i] < 0 || value == "" {
+				continue
+			}
+			if err := setValue(row.Field(columns[i]), value, time.RFC3339); err != nil {
+				return fmt.Errorf("csv: line %d, column %q: %w", line+2, records[0][i], err)
+			}
+		}
+		if slice.Type().Elem().Kind() == reflect.Pointer {
+			row = row.Addr()
+		}
+		slice.Set(reflect.Append(slice, row))
+	}
+	return nil
+}
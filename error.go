@@ -0,0 +1,92 @@
+package zinc
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPError is an error carrying the status code and message sent to the client.
+type HTTPError struct {
+	Code    int         `json:"-"`
+	Message string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
+	Err     error       `json:"-"`
+}
+
+// NewHTTPError creates an HTTPError. The message defaults to the status text.
+func NewHTTPError(code int, message ...string) *HTTPError {
+	he := &HTTPError{Code: code, Message: http.StatusText(code)}
+	if len(message) > 0 {
+		he.Message = message[0]
+	}
+	return he
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// StatusCode returns the HTTP status code of the error.
+func (e *HTTPError) StatusCode() int {
+	return e.Code
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorHandler writes the response for an error returned by a handler.
+type ErrorHandler func(c *Context, err error)
+
+// DefaultErrorHandler responds with the error converted to an HTTPError as JSON.
+// Errors without a status code are reported as 500 without exposing their message.
+func DefaultErrorHandler(c *Context, err error) {
+	if c.Written() {
+		return
+	}
+	he := toHTTPError(err)
+	c.Status(he.Code).JSON(he)
+}
+
+// Error passes err to the configured ErrorHandler.
+func (c *Context) Error(err error) {
+	if handler := c.config().ErrorHandler; handler != nil {
+		handler(c, err)
+		return
+	}
+	DefaultErrorHandler(c, err)
+}
+
+// toHTTPError converts an error into an HTTPError.
+// Validation and binding errors keep their field details.
+func toHTTPError(err error) *HTTPError {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he
+	}
+
+	var verrs ValidationErrors
+	if errors.As(err, &verrs) {
+		return &HTTPError{Code: verrs.StatusCode(), Message: http.StatusText(verrs.StatusCode()), Details: verrs, Err: err}
+	}
+
+	var berrs BindErrors
+	if errors.As(err, &berrs) {
+		return &HTTPError{Code: berrs.StatusCode(), Message: http.StatusText(berrs.StatusCode()), Details: berrs, Err: err}
+	}
+
+	var sc interface{ StatusCode() int }
+	if errors.As(err, &sc) {
+		return &HTTPError{Code: sc.StatusCode(), Message: err.Error(), Err: err}
+	}
+
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return &HTTPError{Code: http.StatusRequestEntityTooLarge, Message: http.StatusText(http.StatusRequestEntityTooLarge), Err: err}
+	}
+
+	return &HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError), Err: err}
+}
@@ -0,0 +1,173 @@
+package zinc
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrFileNotFound is returned when a file does not exist or is a directory.
+	ErrFileNotFound = NewHTTPError(http.StatusNotFound)
+	// ErrFileOutsideRoot is returned for paths that escape Config.FileRoot.
+	ErrFileOutsideRoot = NewHTTPError(http.StatusForbidden)
+)
+
+// File serves a file, honouring Range, If-Range and conditional request headers.
+// When Config.FileRoot is set, path is resolved inside it and paths escaping it
+// are refused. Errors are passed to Context.Error and returned.
+func (c *Context) File(path string) error {
+	return c.serveFile(path, "", "")
+}
+
+// Attachment serves a file for download under downloadName,
+// which defaults to the file's base name.
+func (c *Context) Attachment(path, downloadName string) error {
+	return c.serveFile(path, "attachment", downloadName)
+}
+
+// Inline serves a file for display in the browser, suggesting name
+// for saving it. The name defaults to the file's base name.
+func (c *Context) Inline(path, name string) error {
+	return c.serveFile(path, "inline", name)
+}
+
+// ServeContent serves content with http.ServeContent, which handles Range
+// requests (including multipart/byteranges), If-Range and conditional
+// requests. The Content-Type is taken from the name's extension unless set.
+// A status set with Status replaces the 200 sent for a full response.
+func (c *Context) ServeContent(name string, modtime time.Time, content io.ReadSeeker) error {
+	if c.Written() {
+		return ErrResponseAlreadySent
+	}
+	c.written = true
+
+	var w http.ResponseWriter = c.Response
+	if c.status != 0 && c.status != http.StatusOK {
+		w = &statusWriter{ResponseWriter: c.Response, status: c.status}
+	}
+	http.ServeContent(w, c.Request, name, modtime, content)
+	return nil
+}
+
+func (c *Context) serveFile(path, disposition, name string) error {
+	if c.Written() {
+		return ErrResponseAlreadySent
+	}
+
+	f, info, err := c.openFile(path)
+	if err != nil {
+		c.Error(err)
+		return err
+	}
+	defer f.Close()
+
+	if disposition != "" {
+		if name == "" {
+			name = info.Name()
+		}
+		c.Response.Header().Set("Content-Disposition", contentDisposition(disposition, name))
+	}
+	return c.ServeContent(info.Name(), info.ModTime(), f)
+}
+
+// openFile opens a regular file, resolving it inside Config.FileRoot when set.
+func (c *Context) openFile(path string) (*os.File, fs.FileInfo, error) {
+	if root := c.config().FileRoot; root != "" {
+		var err error
+		if path, err = resolveInRoot(root, path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrFileNotFound
+		}
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, nil, ErrFileNotFound
+	}
+	return f, info, nil
+}
+
+// resolveInRoot joins a slash-separated path to root, refusing absolute paths,
+// ".." elements and symbolic links that lead outside the root.
+func resolveInRoot(root, path string) (string, error) {
+	name := filepath.FromSlash(strings.TrimPrefix(path, "/"))
+	if !filepath.IsLocal(name) {
+		return "", ErrFileOutsideRoot
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(filepath.Join(realRoot, name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", ErrFileNotFound
+		}
+		return "", err
+	}
+	if rel, err := filepath.Rel(realRoot, real); err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return "", ErrFileOutsideRoot
+	}
+	return real, nil
+}
+
+// contentDisposition formats a Content-Disposition header. Names that are not
+// plain ASCII get an ASCII fallback and an RFC 5987 encoded filename* parameter.
+func contentDisposition(disposition, name string) string {
+	fallback := make([]byte, 0, len(name))
+	ascii := true
+	for _, r := range name {
+		switch {
+		case r == '"' || r == '\\':
+			fallback = append(fallback, '\\', byte(r))
+		case r < 0x20 || r == 0x7f:
+			ascii = false
+		case r > 0x7e:
+			fallback = append(fallback, '_')
+			ascii = false
+		default:
+			fallback = append(fallback, byte(r))
+		}
+	}
+
+	header := disposition + `; filename="` + string(fallback) + `"`
+	if !ascii {
+		header += "; filename*=UTF-8''" + encodeRFC5987(name)
+	}
+	return header
+}
+
+// encodeRFC5987 percent-encodes every byte that is not an attr-char.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || strings.IndexByte("!#$&+-.^_`|~", ch) >= 0 {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&0x0f])
+	}
+	return b.String()
+}
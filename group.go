@@ -4,58 +4,99 @@ import "strings"
 
 // Group represents a group of routes with a common prefix.
 type Group struct {
-	prefix string
-	app    *App
+	prefix     string
+	app        *App
+	matchers   []Matcher
+	middleware []Middleware
+	parent     *Group
 }
 
 // Group creates a new group with a given prefix.
-func (g *Group) Group(prefix string) *Group {
+// Matchers are inherited from the parent group and applied to every route in the group.
+func (g *Group) Group(prefix string, matchers ...Matcher) *Group {
 	fullPrefix := g.prefix + "/" + strings.Trim(prefix, "/")
 	return &Group{
-		prefix: fullPrefix,
-		app:    g.app,
+		prefix:   fullPrefix,
+		app:      g.app,
+		matchers: append(append([]Matcher{}, g.matchers...), matchers...),
+		parent:   g,
 	}
 }
 
 // Group creates a new group with a given prefix.
-func (a *App) Group(prefix string) *Group {
+// Matchers are applied to every route in the group.
+func (a *App) Group(prefix string, matchers ...Matcher) *Group {
 	return &Group{
-		prefix: strings.Trim(prefix, "/"),
-		app:    a,
+		prefix:   strings.Trim(prefix, "/"),
+		app:      a,
+		matchers: matchers,
 	}
 }
 
-func (g *Group) Get(path string, handler interface{}) {
+// Use adds middleware that runs before the handlers of routes registered
+// on the group afterwards, including those of its subgroups, whether they
+// were created before or after Use is called.
+func (g *Group) Use(middleware ...Middleware) {
+	g.middleware = append(g.middleware, middleware...)
+}
+
+// allMiddleware returns the middleware of the group's ancestors and then
+// its own, as they stand when a route is registered.
+func (g *Group) allMiddleware() []Middleware {
+	if g.parent == nil {
+		return append([]Middleware{}, g.middleware...)
+	}
+	return append(g.parent.allMiddleware(), g.middleware...)
+}
+
+// routeGroup records the group a route was registered through.
+type routeGroup string
+
+// handlers prepends the group's prefix, matchers and middleware to the route handlers.
+func (g *Group) handlers(handlers []interface{}) []interface{} {
+	middleware := g.allMiddleware()
+	all := make([]interface{}, 0, len(g.matchers)+len(middleware)+len(handlers)+1)
+	all = append(all, routeGroup("/"+g.prefix))
+	for _, m := range g.matchers {
+		all = append(all, m)
+	}
+	for _, m := range middleware {
+		all = append(all, m)
+	}
+	return append(all, handlers...)
+}
+
+func (g *Group) Get(path string, handlers ...interface{}) {
 	fullPath := "/" + g.prefix + "/" + strings.Trim(path, "/")
-	g.app.Get(fullPath, handler)
+	g.app.Get(fullPath, g.handlers(handlers)...)
 }
 
-func (g *Group) Post(path string, handler interface{}) {
+func (g *Group) Post(path string, handlers ...interface{}) {
 	fullPath := "/" + g.prefix + "/" + strings.Trim(path, "/")
-	g.app.Post(fullPath, handler)
+	g.app.Post(fullPath, g.handlers(handlers)...)
 }
 
-func (g *Group) Put(path string, handler interface{}) {
+func (g *Group) Put(path string, handlers ...interface{}) {
 	fullPath := "/" + g.prefix + "/" + strings.Trim(path, "/")
-	g.app.Put(fullPath, handler)
+	g.app.Put(fullPath, g.handlers(handlers)...)
 }
 
-func (g *Group) Delete(path string, handler interface{}) {
+func (g *Group) Delete(path string, handlers ...interface{}) {
 	fullPath := "/" + g.prefix + "/" + strings.Trim(path, "/")
-	g.app.Delete(fullPath, handler)
+	g.app.Delete(fullPath, g.handlers(handlers)...)
 }
 
-func (g *Group) Patch(path string, handler interface{}) {
+func (g *Group) Patch(path string, handlers ...interface{}) {
 	fullPath := "/" + g.prefix + "/" + strings.Trim(path, "/")
-	g.app.Patch(fullPath, handler)
+	g.app.Patch(fullPath, g.handlers(handlers)...)
 }
 
-func (g *Group) Head(path string, handler interface{}) {
+func (g *Group) Head(path string, handlers ...interface{}) {
 	fullPath := "/" + g.prefix + "/" + strings.Trim(path, "/")
-	g.app.Head(fullPath, handler)
+	g.app.Head(fullPath, g.handlers(handlers)...)
 }
 
-func (g *Group) Options(path string, handler interface{}) {
+func (g *Group) Options(path string, handlers ...interface{}) {
 	fullPath := "/" + g.prefix + "/" + strings.Trim(path, "/")
-	g.app.Options(fullPath, handler)
+	g.app.Options(fullPath, g.handlers(handlers)...)
 }
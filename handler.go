@@ -0,0 +1,81 @@
+package zinc
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// TypedHandler is a handler created by Handle.
+// It records its request and response types so routes can be described
+// in generated API documents.
+type TypedHandler struct {
+	handler  RouteHandler
+	request  reflect.Type
+	response reflect.Type
+}
+
+// Handle adapts a typed function to a route handler.
+// The request is bound into Req with Context.Bind (or decoded with Context.Body
+// for non-struct types) and validated; the returned Res is sent with
+// Context.Negotiate in the format requested by the Accept header.
+// Errors, including binding and validation failures, are passed to the
+// configured ErrorHandler. With DefaultValidator, adding the route panics
+// if Req has invalid `validate` tags.
+//
+//	app.Post("/users", zinc.Handle(func(c *zinc.Context, req CreateUser) (User, error) {
+//		...
+//	}))
+func Handle[Req, Res any](fn func(c *Context, req Req) (Res, error)) *TypedHandler {
+	return &TypedHandler{
+		handler: func(c *Context) {
+			var req Req
+			if err := bindRequest(c, &req); err != nil {
+				c.Error(err)
+				return
+			}
+
+			res, err := fn(c, req)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			if c.Written() {
+				return
+			}
+			if err := c.Negotiate(res); err != nil && !c.Written() {
+				c.Error(err)
+			}
+		},
+		request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		response: reflect.TypeOf((*Res)(nil)).Elem(),
+	}
+}
+
+// bindRequest binds the request into the value pointed to by v.
+// Errors without a status code are reported as 400 Bad Request.
+func bindRequest(c *Context, v interface{}) error {
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() == reflect.Pointer && rv.Type().Elem().Kind() == reflect.Struct {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		v = rv.Interface()
+		rv = rv.Elem()
+	}
+
+	var err error
+	switch {
+	case rv.Kind() == reflect.Struct:
+		err = c.Bind(v)
+	case c.Request.Body != nil && c.Request.Body != http.NoBody && c.Request.ContentLength != 0:
+		err = c.Body(v)
+	}
+
+	if err == nil {
+		return nil
+	}
+	var sc interface{ StatusCode() int }
+	if errors.As(err, &sc) {
+		return err
+	}
+	return &HTTPError{Code: http.StatusBadRequest, Message: err.Error(), Err: err}
+}
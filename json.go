@@ -0,0 +1,90 @@
+package zinc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// JSONCodec creates JSON encoders and decoders.
+// The standard library is used by default; sonic, goccy/go-json and jsoniter
+// can be plugged in with a small adapter returning their encoder and decoder types.
+type JSONCodec interface {
+	NewEncoder(w io.Writer) JSONEncoder
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+// JSONEncoder is the subset of *json.Encoder used by zinc.
+type JSONEncoder interface {
+	Encode(v interface{}) error
+	SetIndent(prefix, indent string)
+	SetEscapeHTML(on bool)
+}
+
+// JSONDecoder is the subset of *json.Decoder used by zinc.
+type JSONDecoder interface {
+	Decode(v interface{}) error
+	DisallowUnknownFields()
+	UseNumber()
+}
+
+// StdJSONCodec is the JSONCodec backed by encoding/json.
+var StdJSONCodec JSONCodec = stdJSONCodec{}
+
+type stdJSONCodec struct{}
+
+func (stdJSONCodec) NewEncoder(w io.Writer) JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+func (stdJSONCodec) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
+
+// encodeJSON encodes v with the configured codec and options.
+func (cfg *Config) encodeJSON(w io.Writer, v interface{}) error {
+	enc := cfg.JSONCodec.NewEncoder(w)
+	if cfg.JSONIndent != "" {
+		enc.SetIndent("", cfg.JSONIndent)
+	}
+	if cfg.JSONDisableHTMLEscape {
+		enc.SetEscapeHTML(false)
+	}
+	return enc.Encode(v)
+}
+
+// decodeJSON decodes into v with the configured codec and options.
+func (cfg *Config) decodeJSON(r io.Reader, v interface{}) error {
+	dec := cfg.JSONCodec.NewDecoder(r)
+	if cfg.JSONDisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if cfg.JSONUseNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(v)
+}
+
+// maxPooledBuffer is the largest buffer returned to the pool,
+// so that one large response does not pin memory.
+const maxPooledBuffer = 64 << 10
+
+// Pool of buffers for encoding responses
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
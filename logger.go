@@ -0,0 +1,182 @@
+package zinc
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"time"
+)
+
+// LogField is an attribute recorded by the Logger middleware.
+type LogField string
+
+// Attributes of the request, also added to Context.Logger.
+const (
+	LogMethod    LogField = "method"
+	LogRoute     LogField = "route"
+	LogPath      LogField = "path"
+	LogIP        LogField = "ip"
+	LogRequestID LogField = "request_id"
+	LogUserAgent LogField = "user_agent"
+)
+
+// Attributes of the response.
+const (
+	LogStatus  LogField = "status"
+	LogLatency LogField = "latency"
+	LogBytes   LogField = "bytes"
+)
+
+// DefaultLogFields are the attributes recorded by default.
+var DefaultLogFields = []LogField{
+	LogMethod, LogRoute, LogPath, LogStatus, LogLatency, LogBytes, LogIP, LogRequestID, LogUserAgent,
+}
+
+// LoggerConfig configures the Logger middleware.
+type LoggerConfig struct {
+	// Logger receives the records. Defaults to slog.Default().
+	Logger *slog.Logger
+
+	// Fields are the attributes recorded. Defaults to DefaultLogFields.
+	Fields []LogField
+
+	// SkipPaths lists request paths not to log, such as health checks.
+	SkipPaths []string
+
+	// Skip reports whether a request should not be logged.
+	// It is called once the response has been written.
+	Skip func(c *Context) bool
+
+	// SampleRate is the fraction of successful requests logged, up to 1.
+	// Responses with a status of 400 or more are always logged. Defaults to
+	// 1, logging every request; a negative value logs only failures.
+	SampleRate float64
+}
+
+// Logger returns middleware that records each request with log/slog once it
+// has been handled. Responses with a 5xx status are logged at error level,
+// those with a 4xx status at warning level and the others at info level.
+// Handlers can log with the request's attributes through Context.Logger.
+func Logger(config ...LoggerConfig) Middleware {
+	var cfg LoggerConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.Fields == nil {
+		cfg.Fields = DefaultLogFields
+	}
+	switch {
+	case cfg.SampleRate == 0, cfg.SampleRate > 1:
+		cfg.SampleRate = 1
+	case cfg.SampleRate < 0:
+		cfg.SampleRate = 0
+	}
+
+	return func(c *Context) {
+		if slices.Contains(cfg.SkipPaths, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.logConfig, c.logger = &cfg, nil
+		c.Next()
+
+		status := c.ResponseStatus()
+		if cfg.Skip != nil && cfg.Skip(c) {
+			return
+		}
+		if status < 400 && cfg.SampleRate < 1 && rand.Float64() >= cfg.SampleRate {
+			return
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		ctx := c.Request.Context()
+		if !cfg.Logger.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := c.logAttrs(cfg.Fields)
+		for _, f := range cfg.Fields {
+			switch f {
+			case LogStatus:
+				attrs = append(attrs, slog.Int(string(f), status))
+			case LogLatency:
+				attrs = append(attrs, slog.Duration(string(f), time.Since(start)))
+			case LogBytes:
+				attrs = append(attrs, slog.Int64(string(f), c.ResponseSize()))
+			}
+		}
+		cfg.Logger.LogAttrs(ctx, level, "request", attrs...)
+	}
+}
+
+// Logger returns a logger with the attributes of the request, using the
+// logger and fields of the Logger middleware when it is installed.
+func (c *Context) Logger() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	logger, fields := slog.Default(), DefaultLogFields
+	if c.logConfig != nil {
+		logger, fields = c.logConfig.Logger, c.logConfig.Fields
+	}
+	attrs := c.logAttrs(fields)
+	args := make([]interface{}, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+	c.logger = logger.With(args...)
+	return c.logger
+}
+
+// logAttrs returns the request attributes among fields.
+// The route is only known once the request has been routed.
+func (c *Context) logAttrs(fields []LogField) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		switch f {
+		case LogMethod:
+			attrs = append(attrs, slog.String(string(f), c.Request.Method))
+		case LogRoute:
+			if c.route != "" {
+				attrs = append(attrs, slog.String(string(f), c.route))
+			}
+		case LogPath:
+			attrs = append(attrs, slog.String(string(f), c.Request.URL.Path))
+		case LogIP:
+			attrs = append(attrs, slog.String(string(f), c.ClientIP()))
+		case LogRequestID:
+			if id := c.requestID; id != "" {
+				attrs = append(attrs, slog.String(string(f), id))
+			}
+		case LogUserAgent:
+			attrs = append(attrs, slog.String(string(f), c.Request.UserAgent()))
+		}
+	}
+	return attrs
+}
+
+// RoutePattern returns the pattern of the route that matched the request,
+// such as "/users/:id", or "" before routing or when no route matched.
+func (c *Context) RoutePattern() string {
+	return c.route
+}
+
+// remoteIP returns the host of a RemoteAddr.
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,148 @@
+package zinc
+
+import (
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Matcher is a request predicate attached to a route or group.
+// Routes that share a method and path are told apart by their matchers:
+// the router picks the first route whose matchers all succeed.
+type Matcher struct {
+	match  func(r *http.Request) bool
+	status int
+}
+
+// Match reports whether the request satisfies the matcher.
+func (m Matcher) Match(r *http.Request) bool {
+	return m.match(r)
+}
+
+// Status returns the status code sent when no route matches because of this matcher.
+func (m Matcher) Status() int {
+	return m.status
+}
+
+// MatchFunc creates a matcher from a custom predicate.
+// The status code is used when no route sharing the path matches.
+func MatchFunc(status int, fn func(r *http.Request) bool) Matcher {
+	return Matcher{match: fn, status: status}
+}
+
+// Header matches requests whose header equals the given value.
+// A failed match results in a 404.
+func Header(name, value string) Matcher {
+	return Matcher{
+		match: func(r *http.Request) bool {
+			return r.Header.Get(name) == value
+		},
+		status: http.StatusNotFound,
+	}
+}
+
+// HeaderRegexp matches requests whose header matches the given pattern.
+// It panics if the pattern does not compile. A failed match results in a 404.
+func HeaderRegexp(name, pattern string) Matcher {
+	re := regexp.MustCompile(pattern)
+	return Matcher{
+		match: func(r *http.Request) bool {
+			values, ok := r.Header[http.CanonicalHeaderKey(name)]
+			if !ok {
+				return false
+			}
+			for _, v := range values {
+				if re.MatchString(v) {
+					return true
+				}
+			}
+			return false
+		},
+		status: http.StatusNotFound,
+	}
+}
+
+// HasQuery matches requests that carry the given query parameter.
+// A failed match results in a 400.
+func HasQuery(name string) Matcher {
+	return Matcher{
+		match: func(r *http.Request) bool {
+			return r.URL.Query().Has(name)
+		},
+		status: http.StatusBadRequest,
+	}
+}
+
+// ContentType matches requests whose body has one of the given media types.
+// Parameters such as charset are ignored. A failed match results in a 415.
+func ContentType(types ...string) Matcher {
+	return Matcher{
+		match: func(r *http.Request) bool {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil {
+				return false
+			}
+			for _, t := range types {
+				if strings.EqualFold(mediaType, t) {
+					return true
+				}
+			}
+			return false
+		},
+		status: http.StatusUnsupportedMediaType,
+	}
+}
+
+// Accepts matches requests whose Accept header allows one of the given media types,
+// such as "application/vnd.acme.v2+json". A failed match results in a 406.
+func Accepts(types ...string) Matcher {
+	return Matcher{
+		match: func(r *http.Request) bool {
+			accept := r.Header.Get("Accept")
+			if accept == "" {
+				return false
+			}
+			for _, t := range types {
+				if acceptsMediaType(accept, t) {
+					return true
+				}
+			}
+			return false
+		},
+		status: http.StatusNotAcceptable,
+	}
+}
+
+// acceptsMediaType reports whether an Accept header allows the media type.
+// Ranges with q=0 are treated as explicit refusals.
+func acceptsMediaType(accept, mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	for _, part := range strings.Split(accept, ",") {
+		rng, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok && strings.Trim(q, "0.") == "" {
+			continue
+		}
+		if rng == mediaType || rng == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(rng, "/*") && strings.HasPrefix(mediaType, rng[:len(rng)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAll reports whether the request satisfies every matcher.
+// On failure it returns the status code of the first matcher that failed.
+func matchAll(matchers []Matcher, r *http.Request) (bool, int) {
+	for _, m := range matchers {
+		if !m.Match(r) {
+			return false, m.status
+		}
+	}
+	return true, 0
+}
@@ -0,0 +1,752 @@
+package zinc
+
+import (
+	"encoding"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RouteDoc describes a route in the generated OpenAPI document.
+// Pass it alongside the route handlers:
+//
+//	app.Get("/users/:id", zinc.RouteDoc{Summary: "Fetch a user", Response: User{}}, getUser)
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// Hidden excludes the route from the document.
+	Hidden bool
+	// Request and Response are values whose types describe the request
+	// parameters and body, and the response body. Typed handlers created
+	// with Handle set these automatically.
+	Request  interface{}
+	Response interface{}
+}
+
+// OpenAPIConfig configures the generated OpenAPI document.
+type OpenAPIConfig struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+
+	// Path serves the JSON document. Defaults to "/openapi.json".
+	Path string
+	// DocsPath serves the documentation viewer. Defaults to "/docs"; "-" disables it.
+	DocsPath string
+}
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+	schemaNamePattern = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// OpenAPI serves an OpenAPI 3.1 document describing the registered routes,
+// along with an offline documentation viewer. The document is generated on
+// request and regenerated when routes are added, so routes may be registered
+// before or after calling OpenAPI.
+func (a *App) OpenAPI(config ...OpenAPIConfig) {
+	cfg := OpenAPIConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/openapi.json"
+	}
+	if cfg.DocsPath == "" {
+		cfg.DocsPath = "/docs"
+	}
+
+	var mu sync.Mutex
+	var spec []byte
+	generation := -1
+	a.Get(cfg.Path, RouteDoc{Hidden: true}, func(c *Context) {
+		mu.Lock()
+		if generation != a.router.generation {
+			b, err := json.Marshal(a.OpenAPISpec(cfg))
+			if err != nil {
+				mu.Unlock()
+				c.Error(err)
+				return
+			}
+			spec, generation = b, a.router.generation
+		}
+		b := spec
+		mu.Unlock()
+		c.JSON(json.RawMessage(b))
+	})
+
+	if cfg.DocsPath != "-" {
+		a.Get(cfg.DocsPath, RouteDoc{Hidden: true}, func(c *Context) {
+			var page strings.Builder
+			openAPIViewer.Execute(&page, cfg)
+			c.HTML(page.String())
+		})
+	}
+}
+
+// OpenAPISpec builds an OpenAPI 3.1 document from the registered routes.
+// Routes that share a method and path but differ by matchers are described
+// by a single operation combining their parameters, bodies and responses.
+func (a *App) OpenAPISpec(cfg OpenAPIConfig) Map {
+	if cfg.Title == "" {
+		cfg.Title = "API"
+	}
+	if cfg.Version == "" {
+		cfg.Version = "1.0.0"
+	}
+
+	gen := &schemaGenerator{schemas: Map{}, names: map[reflect.Type]string{}}
+	paths := Map{}
+
+	type entry struct {
+		path   string
+		method string
+		routes []*Route
+	}
+	var entries []entry
+	for method, methodRoutes := range a.router.routes {
+		for path, variants := range methodRoutes {
+			var routes []*Route
+			for _, route := range variants {
+				if !route.doc.Hidden {
+					routes = append(routes, route)
+				}
+			}
+			if len(routes) > 0 {
+				entries = append(entries, entry{path, method, routes})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].path != entries[j].path {
+			return entries[i].path < entries[j].path
+		}
+		return entries[i].method < entries[j].method
+	})
+
+	for _, e := range entries {
+		path := openAPIPath(e.path)
+		item, _ := paths[path].(Map)
+		if item == nil {
+			item = Map{}
+			paths[path] = item
+		}
+		op := gen.operation(e.routes[0])
+		for _, route := range e.routes[1:] {
+			mergeOperation(op, gen.operation(route))
+		}
+		item[strings.ToLower(e.method)] = op
+	}
+
+	info := Map{"title": cfg.Title, "version": cfg.Version}
+	if cfg.Description != "" {
+		info["description"] = cfg.Description
+	}
+
+	doc := Map{
+		"openapi": "3.1.0",
+		"info":    info,
+		"paths":   paths,
+	}
+	if len(cfg.Servers) > 0 {
+		servers := make([]Map, len(cfg.Servers))
+		for i, url := range cfg.Servers {
+			servers[i] = Map{"url": url}
+		}
+		doc["servers"] = servers
+	}
+	if len(gen.schemas) > 0 {
+		doc["components"] = Map{"schemas": gen.schemas}
+	}
+	return doc
+}
+
+// openAPIPath converts a route path such as /users/:id to /users/{id}.
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if name, ok := pathParamName(part); ok {
+			parts[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// pathParamName returns the parameter name of a route path segment.
+func pathParamName(part string) (string, bool) {
+	if part == "" {
+		return "", false
+	}
+	switch part[0] {
+	case paramIdentifier:
+		return part[1:], true
+	case wildcardIdentifier:
+		if part == "*" {
+			return "path", true
+		}
+		return part[1:], true
+	}
+	return "", false
+}
+
+// schemaGenerator builds JSON schemas, collecting named structs as components.
+type schemaGenerator struct {
+	schemas Map
+	names   map[reflect.Type]string
+}
+
+func (g *schemaGenerator) operation(route *Route) Map {
+	doc := route.doc
+	op := Map{"operationId": operationID(route.method, route.path)}
+	if doc.Summary != "" {
+		op["summary"] = doc.Summary
+	}
+	if doc.Description != "" {
+		op["description"] = doc.Description
+	}
+	if doc.Deprecated {
+		op["deprecated"] = true
+	}
+	switch {
+	case len(doc.Tags) > 0:
+		op["tags"] = doc.Tags
+	case route.group != "":
+		op["tags"] = []string{route.group}
+	}
+
+	request, response := route.request, route.response
+	if doc.Request != nil {
+		request = reflect.TypeOf(doc.Request)
+	}
+	if doc.Response != nil {
+		response = reflect.TypeOf(doc.Response)
+	}
+
+	params, body := g.requestSchemas(request)
+
+	// Declare path parameters that the request type does not describe
+	declared := map[string]bool{}
+	for _, p := range params {
+		if p["in"] == BindPath {
+			declared[p["name"].(string)] = true
+		}
+	}
+	var pathParams []Map
+	for _, part := range strings.Split(route.path, "/") {
+		if name, ok := pathParamName(part); ok && !declared[name] {
+			pathParams = append(pathParams, Map{
+				"name": name, "in": BindPath, "required": true, "schema": Map{"type": "string"},
+			})
+		}
+	}
+	params = append(pathParams, params...)
+
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if body != nil {
+		op["requestBody"] = body
+	}
+
+	ok := Map{"description": http.StatusText(http.StatusOK)}
+	if response != nil {
+		ok["content"] = Map{"application/json": Map{"schema": g.schema(response)}}
+	}
+	responses := Map{"200": ok}
+	if route.request != nil || route.response != nil {
+		responses["default"] = Map{
+			"description": "Error",
+			"content":     Map{"application/json": Map{"schema": g.schema(reflect.TypeOf(HTTPError{}))}},
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// mergeOperation adds the operation of another route variant to op.
+// Documentation fields are taken from the first variant that sets them.
+// Parameters not declared by every variant become optional, and differing
+// body and response schemas are combined with oneOf.
+func mergeOperation(op, other Map) {
+	for _, key := range []string{"summary", "description", "tags"} {
+		if _, ok := op[key]; !ok && other[key] != nil {
+			op[key] = other[key]
+		}
+	}
+	if other["deprecated"] == nil {
+		delete(op, "deprecated")
+	}
+
+	params, _ := op["parameters"].([]Map)
+	otherParams, _ := other["parameters"].([]Map)
+	for _, p := range params {
+		if findParameter(otherParams, p) == nil {
+			p["required"] = false
+		}
+	}
+	for _, p := range otherParams {
+		if existing := findParameter(params, p); existing != nil {
+			if p["required"] != true {
+				existing["required"] = false
+			}
+			continue
+		}
+		p["required"] = p["in"] == BindPath
+		params = append(params, p)
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	body, _ := op["requestBody"].(Map)
+	otherBody, _ := other["requestBody"].(Map)
+	switch {
+	case body == nil && otherBody != nil:
+		otherBody["required"] = false
+		op["requestBody"] = otherBody
+	case body != nil && otherBody == nil:
+		body["required"] = false
+	case body != nil:
+		mergeContent(body["content"].(Map), otherBody["content"].(Map))
+	}
+
+	responses := op["responses"].(Map)
+	for code, r := range other["responses"].(Map) {
+		existing, ok := responses[code].(Map)
+		if !ok {
+			responses[code] = r
+			continue
+		}
+		otherContent, _ := r.(Map)["content"].(Map)
+		if otherContent == nil {
+			continue
+		}
+		if content, ok := existing["content"].(Map); ok {
+			mergeContent(content, otherContent)
+		} else {
+			existing["content"] = otherContent
+		}
+	}
+}
+
+// findParameter returns the parameter in params with the name and location of p.
+func findParameter(params []Map, p Map) Map {
+	for _, q := range params {
+		if q["name"] == p["name"] && q["in"] == p["in"] {
+			return q
+		}
+	}
+	return nil
+}
+
+// mergeContent adds the media types of other to content, combining
+// differing schemas of a media type with oneOf.
+func mergeContent(content, other Map) {
+	for mediaType, m := range other {
+		existing, ok := content[mediaType].(Map)
+		if !ok {
+			content[mediaType] = m
+			continue
+		}
+		schema := m.(Map)["schema"]
+		var variants []interface{}
+		if s, ok := existing["schema"].(Map); ok && len(s) == 1 && s["oneOf"] != nil {
+			variants = s["oneOf"].([]interface{})
+		} else {
+			variants = []interface{}{existing["schema"]}
+		}
+		if !slices.ContainsFunc(variants, func(v interface{}) bool { return reflect.DeepEqual(v, schema) }) {
+			existing["schema"] = Map{"oneOf": append(variants, schema)}
+		}
+	}
+}
+
+// requestSchemas describes the parameters and body of a request type.
+// Fields tagged path, query or header become parameters, form fields a form
+// body and the remaining fields a JSON body.
+func (g *schemaGenerator) requestSchemas(t reflect.Type) ([]Map, Map) {
+	if t == nil {
+		return nil, nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, Map{
+			"required": true,
+			"content":  Map{"application/json": Map{"schema": g.schema(t)}},
+		}
+	}
+
+	var params []Map
+	form := Map{}
+	var formRequired []string
+	for _, f := range cachedBindFields(t) {
+		sf := t.FieldByIndex(f.index)
+		schema := g.fieldSchema(sf)
+		required := f.source == BindPath || fieldValidation(sf).required
+		if f.source == BindForm {
+			form[f.key] = schema
+			if required {
+				formRequired = append(formRequired, f.key)
+			}
+			continue
+		}
+		params = append(params, Map{"name": f.key, "in": f.source, "required": required, "schema": schema})
+	}
+
+	content := Map{}
+	if len(form) > 0 {
+		schema := Map{"type": "object", "properties": form}
+		if len(formRequired) > 0 {
+			schema["required"] = formRequired
+		}
+		content["application/x-www-form-urlencoded"] = Map{"schema": schema}
+	}
+	if len(params) == 0 && len(form) == 0 {
+		if t.NumField() > 0 {
+			content["application/json"] = Map{"schema": g.schema(t)}
+		}
+	} else if props, required := g.properties(t, true); len(props) > 0 {
+		schema := Map{"type": "object", "properties": props}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		content["application/json"] = Map{"schema": schema}
+	}
+
+	if len(content) == 0 {
+		return params, nil
+	}
+	return params, Map{"required": true, "content": content}
+}
+
+// schema returns the JSON schema of a type.
+func (g *schemaGenerator) schema(t reflect.Type) Map {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return Map{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return Map{"type": "integer", "format": "int64"}
+	case t == rawMessageType:
+		return Map{}
+	case t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(textMarshalerType):
+		return Map{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Map{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return Map{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return Map{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return Map{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return Map{"type": "number", "format": "double"}
+	case reflect.String:
+		return Map{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Map{"type": "string", "contentEncoding": "base64"}
+		}
+		return Map{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return Map{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	return Map{}
+}
+
+// structSchema returns a reference to a named struct component,
+// or an inline schema for anonymous structs.
+func (g *schemaGenerator) structSchema(t reflect.Type) Map {
+	if t.Name() == "" {
+		return g.objectSchema(t)
+	}
+
+	name, ok := g.names[t]
+	if !ok {
+		name = schemaNamePattern.ReplaceAllString(t.Name(), "_")
+		if _, taken := g.schemas[name]; taken {
+			name = schemaNamePattern.ReplaceAllString(t.String(), "_")
+		}
+		g.names[t] = name
+		g.schemas[name] = Map{} // placeholder for recursive types
+		g.schemas[name] = g.objectSchema(t)
+	}
+	return Map{"$ref": "#/components/schemas/" + name}
+}
+
+func (g *schemaGenerator) objectSchema(t reflect.Type) Map {
+	props, required := g.properties(t, false)
+	schema := Map{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// properties returns the JSON properties of a struct.
+// When bodyOnly is set, fields bound from path, query, header or form are skipped.
+func (g *schemaGenerator) properties(t reflect.Type, bodyOnly bool) (Map, []string) {
+	props := Map{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded, req := g.properties(ft, bodyOnly)
+				for k, v := range embedded {
+					props[k] = v
+				}
+				required = append(required, req...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if bodyOnly && isBoundField(sf) {
+			continue
+		}
+
+		name = fieldName(sf)
+		props[name] = g.fieldSchema(sf)
+		if fieldValidation(sf).required {
+			required = append(required, name)
+		}
+	}
+	return props, required
+}
+
+// isBoundField reports whether a field is bound from outside the JSON body.
+func isBoundField(sf reflect.StructField) bool {
+	for _, source := range []string{BindPath, BindQuery, BindHeader, BindForm} {
+		if _, ok := sf.Tag.Lookup(source); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldSchema returns the schema of a struct field including its validation constraints.
+func (g *schemaGenerator) fieldSchema(sf reflect.StructField) Map {
+	schema := g.schema(sf.Type)
+	f := fieldValidation(sf)
+	applyConstraints(schema, f.rules)
+
+	if f.dive != nil {
+		if items, ok := schema["items"].(Map); ok {
+			applyConstraints(items, f.dive.rules)
+		} else if values, ok := schema["additionalProperties"].(Map); ok {
+			applyConstraints(values, f.dive.rules)
+		}
+	}
+	return schema
+}
+
+// fieldValidation returns the validation rules of a struct field.
+// Invalid tags are documented without constraints; Handle rejects them.
+func fieldValidation(sf reflect.StructField) *validationField {
+	f, err := parseValidationTag(sf.Tag.Get("validate"))
+	if err != nil {
+		return &validationField{}
+	}
+	return f
+}
+
+// applyConstraints maps validation rules onto JSON schema keywords.
+func applyConstraints(schema Map, rules []validationRule) {
+	if _, ref := schema["$ref"]; ref {
+		return
+	}
+
+	var minKey, maxKey string
+	switch schema["type"] {
+	case "string":
+		minKey, maxKey = "minLength", "maxLength"
+	case "array":
+		minKey, maxKey = "minItems", "maxItems"
+	case "object":
+		minKey, maxKey = "minProperties", "maxProperties"
+	default:
+		minKey, maxKey = "minimum", "maximum"
+	}
+
+	for _, rule := range rules {
+		n, _ := strconv.ParseFloat(rule.param, 64)
+		switch rule.name {
+		case "min", "gte":
+			schema[minKey] = n
+		case "max", "lte":
+			schema[maxKey] = n
+		case "len":
+			schema[minKey], schema[maxKey] = n, n
+		case "gt":
+			if minKey == "minimum" {
+				schema["exclusiveMinimum"] = n
+			} else {
+				schema[minKey] = n + 1
+			}
+		case "lt":
+			if maxKey == "maximum" {
+				schema["exclusiveMaximum"] = n
+			} else {
+				schema[maxKey] = n - 1
+			}
+		case "email":
+			schema["format"] = "email"
+		case "url":
+			schema["format"] = "uri"
+		case "uuid":
+			schema["format"] = "uuid"
+		case "regex":
+			schema["pattern"] = rule.param
+		case "oneof":
+			options := strings.Fields(rule.param)
+			enum := make([]interface{}, len(options))
+			for i, o := range options {
+				enum[i] = o
+				if schema["type"] == "integer" || schema["type"] == "number" {
+					if n, err := strconv.ParseFloat(o, 64); err == nil {
+						enum[i] = n
+					}
+				}
+			}
+			schema["enum"] = enum
+		}
+	}
+}
+
+// operationID derives an operation ID such as getUsersById from a method and path.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.Split(path, "/") {
+		if name, ok := pathParamName(part); ok {
+			b.WriteString("By")
+			part = name
+		}
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// openAPIViewer renders the document without loading any external assets.
+var openAPIViewer = template.Must(template.New("openapi").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>{{if .Title}}{{.Title}}{{else}}API{{end}} reference</title>
+<style>
+body { margin: 0; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; color: #1f2937; background: #f9fafb; }
+main { max-width: 960px; margin: 0 auto; padding: 2rem 1rem; }
+h1 { margin: 0 0 .25rem; } .muted { color: #6b7280; }
+details { background: #fff; border: 1px solid #e5e7eb; border-radius: .5rem; margin: .5rem 0; }
+summary { cursor: pointer; padding: .75rem 1rem; font-family: ui-monospace, monospace; }
+.method { display: inline-block; min-width: 4.5rem; font-weight: 700; text-transform: uppercase; }
+.get { color: #2563eb; } .post { color: #16a34a; } .put, .patch { color: #d97706; } .delete { color: #dc2626; }
+.body { padding: 0 1rem 1rem; } table { border-collapse: collapse; width: 100%; }
+td, th { text-align: left; padding: .25rem .5rem; border-bottom: 1px solid #f3f4f6; }
+pre { background: #111827; color: #e5e7eb; padding: .75rem; border-radius: .375rem; overflow: auto; }
+h2 { font-size: 1.1rem; margin-top: 2rem; }
+</style>
+</head>
+<body>
+<main>
+<h1 id="title">API</h1>
+<p class="muted" id="description"></p>
+<div id="operations"></div>
+</main>
+<script>
+const specPath = {{.Path}};
+const esc = s => String(s).replace(/[&<>"']/g, c => ({"&":"&amp;","<":"&lt;",">":"&gt;",'"':"&quot;","'":"&#39;"}[c]));
+fetch(specPath).then(r => r.json()).then(spec => {
+  document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+  document.getElementById("description").textContent = spec.info.description || "";
+  const schemas = (spec.components || {}).schemas || {};
+  const resolve = (s, seen = new Set()) => {
+    if (!s || typeof s !== "object") return s;
+    if (s.$ref) {
+      const name = s.$ref.split("/").pop();
+      if (seen.has(name)) return {$ref: name};
+      return resolve(schemas[name], new Set([...seen, name]));
+    }
+    const out = Array.isArray(s) ? [] : {};
+    for (const k in s) out[k] = resolve(s[k], seen);
+    return out;
+  };
+  const groups = {};
+  for (const [path, item] of Object.entries(spec.paths)) {
+    for (const [method, op] of Object.entries(item)) {
+      const tag = (op.tags || ["default"])[0];
+      (groups[tag] = groups[tag] || []).push({path, method, op});
+    }
+  }
+  let html = "";
+  for (const [tag, ops] of Object.entries(groups)) {
+    html += "<h2>" + esc(tag) + "</h2>";
+    for (const {path, method, op} of ops) {
+      html += "<details><summary><span class='method " + method + "'>" + method + "</span>" + esc(path) +
+        (op.summary ? " <span class='muted'>" + esc(op.summary) + "</span>" : "") + "</summary><div class='body'>";
+      if (op.description) html += "<p>" + esc(op.description) + "</p>";
+      if (op.parameters) {
+        html += "<h3>Parameters</h3><table><tr><th>Name</th><th>In</th><th>Type</th><th>Required</th></tr>";
+        for (const p of op.parameters) {
+          html += "<tr><td>" + esc(p.name) + "</td><td>" + esc(p.in) + "</td><td>" + esc(p.schema.type || "") + "</td><td>" + (p.required ? "yes" : "no") + "</td></tr>";
+        }
+        html += "</table>";
+      }
+      if (op.requestBody) {
+        for (const [type, media] of Object.entries(op.requestBody.content)) {
+          html += "<h3>Request body <span class='muted'>" + esc(type) + "</span></h3><pre>" + esc(JSON.stringify(resolve(media.schema), null, 2)) + "</pre>";
+        }
+      }
+      for (const [code, res] of Object.entries(op.responses)) {
+        html += "<h3>" + esc(code) + " <span class='muted'>" + esc(res.description) + "</span></h3>";
+        for (const media of Object.values(res.content || {})) {
+          html += "<pre>" + esc(JSON.stringify(resolve(media.schema), null, 2)) + "</pre>";
+        }
+      }
+      html += "</div></details>";
+    }
+  }
+  document.getElementById("operations").innerHTML = html;
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,151 @@
+package zinc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidRedirectCode is returned when redirecting with a status outside 300-308.
+var ErrInvalidRedirectCode = errors.New("invalid redirect status code")
+
+// Redirect redirects the client to url with the given 3xx status code.
+// Relative URLs are resolved against the request path.
+func (c *Context) Redirect(code int, url string) error {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		return ErrInvalidRedirectCode
+	}
+	if c.Written() {
+		return ErrResponseAlreadySent
+	}
+	c.written = true
+	http.Redirect(c.Response, c.Request, url, code)
+	return nil
+}
+
+// RedirectToRoute redirects to the named route, filling in its path parameters.
+// After a form submission it responds with 303 See Other so that the client
+// follows up with a GET; otherwise it responds with 302 Found.
+func (c *Context) RedirectToRoute(name string, params map[string]string) error {
+	if c.app == nil {
+		return fmt.Errorf("route %q not found", name)
+	}
+	url, err := c.app.URL(name, params)
+	if err != nil {
+		return err
+	}
+	return c.Redirect(c.redirectStatus(), url)
+}
+
+// RedirectBack redirects to the page in the Referer header, or to fallback
+// when it is missing or points to another host.
+// The status code is chosen as in RedirectToRoute.
+func (c *Context) RedirectBack(fallback string) error {
+	target := fallback
+	if ref, err := url.Parse(c.Request.Referer()); err == nil && ref.Host != "" &&
+		strings.EqualFold(ref.Host, c.Host()) && (ref.Scheme == "http" || ref.Scheme == "https") {
+		target = ref.RequestURI()
+	}
+	return c.Redirect(c.redirectStatus(), target)
+}
+
+// redirectStatus returns 303 for unsafe methods and 302 otherwise.
+func (c *Context) redirectStatus() int {
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return http.StatusFound
+	}
+	return http.StatusSeeOther
+}
+
+// URL builds the path of the named route, filling in its path parameters.
+func (a *App) URL(name string, params map[string]string) (string, error) {
+	path, ok := a.router.names[name]
+	if !ok {
+		return "", fmt.Errorf("route %q not found", name)
+	}
+	return buildPath(path, params)
+}
+
+// Redirect registers a redirect from one path to another for all common methods.
+// Parameters in from are substituted into to, and the query string is kept:
+//
+//	app.Redirect("/old/:id", "/new/:id", http.StatusMovedPermanently)
+//
+// It panics if to uses a parameter that from does not define.
+func (a *App) Redirect(from, to string, code int) {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		panic("redirect status code must be between 300 and 308")
+	}
+	defined := pathParams(from)
+	for name := range pathParams(to) {
+		if !defined[name] {
+			panic(fmt.Sprintf("redirect target %q uses parameter %q, which %q does not define", to, name, from))
+		}
+	}
+
+	handler := func(c *Context) {
+		target, err := buildPath(to, c.PathParams)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if query := c.Request.URL.RawQuery; query != "" {
+			if strings.Contains(target, "?") {
+				target += "&" + query
+			} else {
+				target += "?" + query
+			}
+		}
+		c.Redirect(code, target)
+	}
+
+	for _, method := range []string{MethodGet, MethodHead, MethodPost, MethodPut, MethodPatch, MethodDelete} {
+		a.router.Add(method, from, RouteDoc{Hidden: true}, handler)
+	}
+}
+
+// pathParams returns the names of the parameters in a route pattern,
+// with "*" standing for the wildcard.
+func pathParams(pattern string) map[string]bool {
+	names := make(map[string]bool)
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "" {
+			continue
+		}
+		switch segment[0] {
+		case paramIdentifier:
+			names[segment[1:]] = true
+		case wildcardIdentifier:
+			names["*"] = true
+		}
+	}
+	return names
+}
+
+// buildPath substitutes parameters into a route pattern. Named parameters
+// are escaped as a single path segment; the wildcard may span several.
+func buildPath(pattern string, params map[string]string) (string, error) {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		switch segment[0] {
+		case paramIdentifier:
+			value, ok := params[segment[1:]]
+			if !ok {
+				return "", fmt.Errorf("missing path parameter %q", segment[1:])
+			}
+			segments[i] = url.PathEscape(value)
+		case wildcardIdentifier:
+			parts := strings.Split(params["*"], "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
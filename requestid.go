@@ -0,0 +1,133 @@
+package zinc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// RequestIDConfig configures the RequestID middleware.
+type RequestIDConfig struct {
+	// Header carries the request ID in requests and responses.
+	// Defaults to "X-Request-ID".
+	Header string
+
+	// Generator returns new request IDs. Defaults to NewUUIDv7.
+	Generator func() string
+}
+
+type requestIDKey struct{}
+
+// maxRequestIDLength bounds the incoming request IDs that are accepted.
+const maxRequestIDLength = 128
+
+// RequestID returns middleware that gives each request an ID, taken from the
+// request header when present and generated otherwise. The ID is echoed in the
+// response header, returned by Context.RequestID, added to the request's
+// context.Context and to the attributes of Context.Logger. Incoming IDs that
+// are longer than 128 bytes or contain characters other than printable ASCII
+// are replaced.
+func RequestID(config ...RequestIDConfig) Middleware {
+	var cfg RequestIDConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-Request-ID"
+	}
+	if cfg.Generator == nil {
+		cfg.Generator = NewUUIDv7
+	}
+
+	return func(c *Context) {
+		id := c.Request.Header.Get(cfg.Header)
+		if !validRequestID(id) {
+			id = cfg.Generator()
+		}
+		c.requestID, c.logger = id, nil
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+		c.Response.Header().Set(cfg.Header, id)
+	}
+}
+
+// RequestID returns the ID given to the request by the RequestID middleware.
+func (c *Context) RequestID() string {
+	return c.requestID
+}
+
+// RequestIDFromContext returns the request ID stored in ctx by the RequestID
+// middleware, or "" if there is none. A *Context can be passed as ctx.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDTransport is an http.RoundTripper that propagates the request ID
+// of an outgoing request's context to the services it calls:
+//
+//	client := &http.Client{Transport: &zinc.RequestIDTransport{}}
+//	req, _ := http.NewRequestWithContext(c, "GET", url, nil)
+//	resp, err := client.Do(req)
+type RequestIDTransport struct {
+	// Base performs the requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Header carries the request ID. Defaults to "X-Request-ID".
+	Header string
+}
+
+// RoundTrip sets the request ID header, unless it is already set,
+// and sends the request with Base.
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	header := t.Header
+	if header == "" {
+		header = "X-Request-ID"
+	}
+
+	if id := RequestIDFromContext(req.Context()); id != "" && req.Header.Get(header) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(header, id)
+	}
+	return base.RoundTrip(req)
+}
+
+// NewUUIDv7 returns a random UUID version 7, as defined by RFC 9562.
+// Its first 48 bits are a millisecond timestamp, so IDs sort by creation time.
+func NewUUIDv7() string {
+	var u [16]byte
+	binary.BigEndian.PutUint64(u[:8], uint64(time.Now().UnixMilli())<<16)
+	rand.Read(u[6:])
+	u[6] = u[6]&0x0f | 0x70 // version 7
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -1,46 +1,31 @@
 package zinc
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
+	"io"
 	"net/http"
+	"strconv"
 )
 
 var ErrResponseAlreadySent = errors.New("response already sent")
 
 func (c *Context) Send(data interface{}) error {
-	if c.written {
-		return ErrResponseAlreadySent
-	}
-	c.written = true
-
-	if c.status == 0 {
-		c.status = http.StatusOK
-	}
-
 	switch v := data.(type) {
 	case string:
-		c.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		c.Response.WriteHeader(c.status)
-		_, err := c.Response.Write([]byte(v))
-		return err
+		return c.sendBytes("text/plain; charset=utf-8", []byte(v))
 	case []byte:
-		c.Response.Header().Set("Content-Type", "application/octet-stream")
-		c.Response.WriteHeader(c.status)
-		_, err := c.Response.Write(v)
-		return err
+		return c.sendBytes("application/octet-stream", v)
 	case nil:
-		c.Response.WriteHeader(c.status)
-		return nil
+		return c.sendBytes("", nil)
 	default:
-		c.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
-		c.Response.WriteHeader(c.status)
-		return json.NewEncoder(c.Response).Encode(data)
+		return c.JSON(data)
 	}
 }
 
-func (c *Context) JSON(data interface{}) error {
-	if c.written {
+// sendBytes writes the status, content type and body.
+func (c *Context) sendBytes(contentType string, body []byte) error {
+	if c.Written() {
 		return ErrResponseAlreadySent
 	}
 	c.written = true
@@ -49,35 +34,121 @@ func (c *Context) JSON(data interface{}) error {
 		c.status = http.StatusOK
 	}
 
-	c.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
-	c.Response.Header().Set("X-Content-Type-Options", "nosniff")
-
+	if contentType != "" {
+		c.Response.Header().Set("Content-Type", contentType)
+	}
 	c.Response.WriteHeader(c.status)
+	if body == nil {
+		return nil
+	}
+	_, err := c.Response.Write(body)
+	return err
+}
+
+func (c *Context) JSON(data interface{}) error {
+	if c.Written() {
+		return ErrResponseAlreadySent
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
 
 	if data == nil {
-		_, err := c.Response.Write([]byte("null"))
+		buf.WriteString("null")
+	} else if err := c.config().encodeJSON(buf, data); err != nil {
 		return err
 	}
 
-	return json.NewEncoder(c.Response).Encode(data)
+	c.Response.Header().Set("X-Content-Type-Options", "nosniff")
+	return c.writeBuffer("application/json; charset=utf-8", buf)
 }
 
-func (c *Context) HTML(data string) error {
+// writeBuffer sends an encoded body with its Content-Length.
+// Encoding into a buffer first lets encoding errors be reported
+// before any part of the response is written.
+func (c *Context) writeBuffer(contentType string, buf *bytes.Buffer) error {
+	if c.Written() {
+		return ErrResponseAlreadySent
+	}
 	c.written = true
-	c.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	if c.status == 0 {
 		c.status = http.StatusOK
 	}
 
+	header := c.Response.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(buf.Len()))
 	c.Response.WriteHeader(c.status)
-	_, err := c.Response.Write([]byte(data))
+	_, err := buf.WriteTo(c.Response)
 	return err
 }
 
+func (c *Context) HTML(data string) error {
+	return c.sendBytes("text/html; charset=utf-8", []byte(data))
+}
+
+// Static serves a file from disk. It is equivalent to File.
 func (c *Context) Static(filepath string) error {
+	return c.File(filepath)
+}
+
+// Stream writes the response incrementally. step is called repeatedly with
+// the response writer until it returns false, and the response is flushed
+// after each call. Streaming stops early if the client disconnects, in which
+// case the request context's error is returned. Set the Content-Type header
+// before calling Stream.
+func (c *Context) Stream(step func(w io.Writer) bool) error {
+	if c.Written() {
+		return ErrResponseAlreadySent
+	}
 	c.written = true
 
-	http.ServeFile(c.Response, c.Request, filepath)
-	return nil
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.Response.WriteHeader(c.status)
+
+	rc := http.NewResponseController(c.Response)
+	done := c.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return c.Request.Context().Err()
+		default:
+		}
+
+		more := step(c.Response)
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// SendReader copies r to the response with the given content type.
+// Content-Length is set when r reports its length, as bytes.Reader and
+// strings.Reader do. The reader is not closed.
+func (c *Context) SendReader(r io.Reader, contentType string) error {
+	if c.Written() {
+		return ErrResponseAlreadySent
+	}
+	c.written = true
+
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+
+	header := c.Response.Header()
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if l, ok := r.(interface{ Len() int }); ok {
+		header.Set("Content-Length", strconv.Itoa(l.Len()))
+	}
+	c.Response.WriteHeader(c.status)
+	_, err := io.Copy(c.Response, r)
+	return err
 }
@@ -1,6 +1,10 @@
 package zinc
 
 import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -29,18 +33,37 @@ type RouteNode struct {
 }
 
 type Route struct {
-	path    string
-	handler RouteHandler
-	method  string
-	parts   []string
+	path     string
+	handler  RouteHandler
+	method   string
+	parts    []string
+	matchers []Matcher
+	request  reflect.Type
+	response reflect.Type
+	group    string
+	doc      RouteDoc
+	name     string
 }
 
+// RouteName names a route so that its URL can be built with App.URL.
+// Pass it alongside the route's handlers.
+type RouteName string
+
 type Middleware func(c *Context)
 
 type Router struct {
-	routes     map[string]map[string]*Route // method -> path -> route
+	routes     map[string]map[string][]*Route // method -> path -> route variants
+	names      map[string]string              // route name -> path
 	router     *RouteNode
 	middleware []Middleware
+
+	// generation counts the routes added, so that documents generated
+	// from the routes can tell when they are stale.
+	generation int
+
+	// checkTags reports whether the request types of typed handlers are
+	// checked for invalid `validate` tags when they are added.
+	checkTags bool
 }
 
 var pathPartsCache = sync.Pool{
@@ -82,19 +105,40 @@ func getPathParts(path string) []string {
 func (r *Router) Add(method, path string, handlers ...interface{}) {
 	// Initialize maps if needed
 	if r.routes == nil {
-		r.routes = make(map[string]map[string]*Route)
+		r.routes = make(map[string]map[string][]*Route)
 	}
 	if r.routes[method] == nil {
-		r.routes[method] = make(map[string]*Route)
+		r.routes[method] = make(map[string][]*Route)
 	}
 
 	// Pre-allocate routeHandlers slice with exact capacity
 	routeHandlers := make([]RouteHandler, 0, len(r.middleware)+len(handlers))
 	routeHandlers = append(routeHandlers, r.middlewareToHandlers()...)
 
+	route := &Route{method: method}
+
+	// Route options are passed alongside handlers
 	for _, handler := range handlers {
-		rh := convertToRouteHandler(handler)
-		routeHandlers = append(routeHandlers, rh)
+		switch h := handler.(type) {
+		case Matcher:
+			route.matchers = append(route.matchers, h)
+		case RouteDoc:
+			route.doc = h
+		case routeGroup:
+			route.group = string(h)
+		case RouteName:
+			route.name = string(h)
+		case *TypedHandler:
+			if r.checkTags {
+				if err := checkValidationTags(h.request); err != nil {
+					panic(err)
+				}
+			}
+			route.request, route.response = h.request, h.response
+			routeHandlers = append(routeHandlers, h.handler)
+		default:
+			routeHandlers = append(routeHandlers, convertToRouteHandler(h))
+		}
 	}
 
 	var mainHandler RouteHandler
@@ -105,14 +149,21 @@ func (r *Router) Add(method, path string, handlers ...interface{}) {
 	path = r.normalizePath(path)
 	parts := getPathParts(path)
 
-	// Store in routes map
-	r.routes[method][path] = &Route{
-		path:    path,
-		handler: mainHandler,
-		method:  method,
-		parts:   parts,
+	route.path = path
+	route.handler = mainHandler
+	route.parts = parts
+
+	if route.name != "" {
+		if r.names == nil {
+			r.names = make(map[string]string)
+		}
+		r.names[route.name] = path
 	}
 
+	// Store in routes map
+	r.routes[method][path] = addVariant(r.routes[method][path], route)
+	r.generation++
+
 	// Update trie storage
 	current := r.router
 	if current == nil {
@@ -156,71 +207,141 @@ func (r *Router) Add(method, path string, handlers ...interface{}) {
 	pathPartsCache.Put(parts)
 }
 
+// addVariant adds a route to the variants registered for a method and path.
+// Routes with more matchers are tried first; a route without matchers
+// replaces any previous route without matchers.
+func addVariant(variants []*Route, route *Route) []*Route {
+	if len(route.matchers) == 0 {
+		for i, v := range variants {
+			if len(v.matchers) == 0 {
+				variants[i] = route
+				return variants
+			}
+		}
+	}
+
+	variants = append(variants, route)
+	sort.SliceStable(variants, func(i, j int) bool {
+		return len(variants[i].matchers) > len(variants[j].matchers)
+	})
+	return variants
+}
+
+// Find returns the handler and path parameters for a method and path.
+// Routes with matchers are matched against a request without headers or query.
 func (r *Router) Find(method, path string) (RouteHandler, map[string]string) {
+	req := &http.Request{Method: method, URL: &url.URL{Path: path}, Header: http.Header{}}
+	handler, params, _ := r.FindRequest(req)
+	return handler, params
+}
+
+// FindRequest returns the handler and path parameters for a request.
+// When routes exist for the path but none of their matchers succeed,
+// the returned handler is nil and status holds the code to respond with.
+func (r *Router) FindRequest(req *http.Request) (handler RouteHandler, params map[string]string, status int) {
+	route, params, status := r.find(req)
+	if route == nil {
+		return nil, nil, status
+	}
+	return route.handler, params, 0
+}
+
+// find returns the route matching a request, or the status to respond with.
+func (r *Router) find(req *http.Request) (*Route, map[string]string, int) {
+	method, path := req.Method, req.URL.Path
+
 	// Try direct lookup first
-	if methodRoutes, ok := r.routes[method]; ok {
-		if route, ok := methodRoutes[path]; ok {
-			return route.handler, nil // Don't allocate params map for static routes
+	variants, ok := r.routes[method][path]
+	var params map[string]string
+	if !ok {
+		// Fall back to trie search for parameterized routes
+		parts := getPathParts(path)
+		params = make(map[string]string)
+		node := r.router.find(parts, params)
+		pathPartsCache.Put(parts)
+
+		if node == nil || node.handler == nil {
+			return nil, nil, http.StatusNotFound
 		}
+		variants = r.routes[method][node.path]
 	}
 
-	// Fall back to trie search for parameterized routes
-	parts := getPathParts(path)
-	params := make(map[string]string)
-	node := r.router.find(parts, params)
+	return selectVariant(variants, req, params)
+}
 
-	if node != nil && node.handler != nil {
-		if matchedRoute := r.routes[method][node.path]; matchedRoute != nil {
-			pathPartsCache.Put(parts)
-			return matchedRoute.handler, params
+// selectVariant picks the first route whose matchers accept the request.
+// If none do, the highest status reported by a failing matcher is returned.
+func selectVariant(variants []*Route, req *http.Request, params map[string]string) (*Route, map[string]string, int) {
+	status := 0
+	for _, route := range variants {
+		ok, code := matchAll(route.matchers, req)
+		if ok {
+			return route, params, 0
+		}
+		if code > status {
+			status = code
 		}
 	}
+	if status == 0 {
+		status = http.StatusNotFound
+	}
 
-	pathPartsCache.Put(parts)
-	return nil, nil
+	return nil, nil, status
 }
 
 func (r *Router) Use(middleware ...Middleware) {
 	r.middleware = append(r.middleware, middleware...)
 }
 
+// chain runs the route handlers as a nested middleware chain,
+// restoring the application chain afterwards.
 func chain(handlers []RouteHandler) RouteHandler {
+	middleware := make([]Middleware, len(handlers))
+	for i, h := range handlers {
+		middleware[i] = Middleware(h)
+	}
 	return func(c *Context) {
-		for _, handler := range handlers {
-			handler(c)
-			if c.written {
-				return
-			}
-		}
+		handlers, index := c.handlers, c.index
+		c.setHandlers(middleware)
+		c.Next()
+		c.handlers, c.index = handlers, index
 	}
 }
 
+// find returns the node with a handler matching parts. Static children are
+// tried before parameters, and parameters before the wildcard, so that a
+// catch-all such as a static directory mounted at "/" does not hide more
+// specific routes.
 func (n *RouteNode) find(parts []string, params map[string]string) *RouteNode {
 	if len(parts) == 0 {
+		if n.handler == nil {
+			return nil
+		}
 		return n
 	}
 
-	part := parts[0]
-	parts = parts[1:]
-
+	part, rest := parts[0], parts[1:]
 	for _, child := range n.children {
-		if child.isWild {
-			params["*"] = strings.Join(append([]string{part}, parts...), "/")
-			return child
+		if !child.isParam && !child.isWild && child.part == part {
+			if match := child.find(rest, params); match != nil {
+				return match
+			}
 		}
-
+	}
+	for _, child := range n.children {
 		if child.isParam {
-			params[child.part] = part
-			if matchChild := child.find(parts, params); matchChild != nil {
-				return matchChild
-			}
-		} else if child.part == part {
-			if matchChild := child.find(parts, params); matchChild != nil {
-				return matchChild
+			if match := child.find(rest, params); match != nil {
+				params[child.part] = part
+				return match
 			}
 		}
 	}
-
+	for _, child := range n.children {
+		if child.isWild && child.handler != nil {
+			params["*"] = strings.Join(parts, "/")
+			return child
+		}
+	}
 	return nil
 }
 
@@ -280,9 +401,11 @@ func (a *App) Trace(path string, handlers ...interface{}) {
 }
 
 func (r *Router) findRoute(path string, method string) *Route {
-	for _, route := range r.routes[method] {
-		if route.path == path {
-			return route
+	for _, variants := range r.routes[method] {
+		for _, route := range variants {
+			if route.path == path {
+				return route
+			}
 		}
 	}
 	return nil
@@ -310,7 +433,9 @@ func convertToRouteHandler(handler interface{}) RouteHandler {
 		return v
 	case Middleware:
 		return RouteHandler(v)
+	case *TypedHandler:
+		return v.handler
 	default:
-		panic("handler must be either a string, RouteHandler, or Middleware")
+		panic("handler must be either a string, RouteHandler, Middleware or TypedHandler")
 	}
 }
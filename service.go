@@ -0,0 +1,421 @@
+package zinc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Lifetime controls how often the constructor of a service is called.
+type Lifetime int
+
+const (
+	// Singleton services are built once per application
+	// and closed by App.Shutdown.
+	Singleton Lifetime = iota
+	// Scoped services are built once per request and closed when it completes.
+	Scoped
+	// Transient services are built every time they are resolved. Those
+	// resolved during a request are closed when it completes.
+	Transient
+)
+
+func (l Lifetime) String() string {
+	switch l {
+	case Singleton:
+		return "singleton"
+	case Scoped:
+		return "scoped"
+	case Transient:
+		return "transient"
+	}
+	return "Lifetime(" + strconv.Itoa(int(l)) + ")"
+}
+
+var (
+	contextType = reflect.TypeFor[*Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// container holds the services of an application, keyed by type.
+type container struct {
+	mu       sync.RWMutex
+	services map[reflect.Type]*service
+
+	closeMu sync.Mutex
+	closers []interface{} // singletons built by the container, in build order
+}
+
+// service is a registered service.
+type service struct {
+	lifetime Lifetime
+	ctor     reflect.Value // called with its dependencies resolved by type
+	deps     []reflect.Type
+	build    func() (interface{}, error)
+
+	// Singleton state.
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+// scope holds the scoped services of a request and the services
+// to close when it completes.
+type scope struct {
+	values  map[reflect.Type]interface{}
+	closers []interface{}
+	closed  bool
+}
+
+func (ct *container) add(t reflect.Type, s *service) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.services == nil {
+		ct.services = make(map[reflect.Type]*service)
+	}
+	ct.services[t] = s
+}
+
+func (ct *container) lookup(t reflect.Type) (*service, bool) {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	s, ok := ct.services[t]
+	return s, ok
+}
+
+// Provide registers svc as the singleton service of type T, replacing any
+// other. T may be an interface type, so that handlers depend on the interface:
+//
+//	zinc.Provide[UserStore](app, postgresStore)
+func Provide[T any](a *App, svc T) {
+	s := &service{lifetime: Singleton, value: svc}
+	// The service is already built, and is not closed by the container.
+	s.once.Do(func() {})
+	a.container.add(reflect.TypeFor[T](), s)
+}
+
+// ProvideLazy registers a constructor for the singleton service of type T.
+// It is called once, when the service is first resolved.
+func ProvideLazy[T any](a *App, fn func() (T, error)) {
+	a.container.add(reflect.TypeFor[T](), &service{lifetime: Singleton, build: func() (interface{}, error) {
+		return fn()
+	}})
+}
+
+// Register registers a constructor for a service with the given lifetime.
+// The constructor is a function returning the service, and optionally an
+// error; the type of its first result is the type the service is resolved by.
+// Its parameters are resolved as services, and scoped and transient services
+// may also take the *Context of the request:
+//
+//	app.Register(zinc.Singleton, func() (*sql.DB, error) {
+//		return sql.Open("postgres", dsn)
+//	})
+//	app.Register(zinc.Scoped, func(db *sql.DB, c *zinc.Context) (*sql.Tx, error) {
+//		return db.BeginTx(c.Request.Context(), nil)
+//	})
+//
+// Services built by the container that implement io.Closer, or have a Close
+// method without results, are closed at the end of their lifetime.
+// Dependencies are checked by CheckServices and when the server starts.
+func (a *App) Register(lifetime Lifetime, constructor interface{}) {
+	ctor := reflect.ValueOf(constructor)
+	ft := ctor.Type()
+	if ft.Kind() != reflect.Func || ft.NumOut() == 0 || ft.NumOut() > 2 ||
+		ft.NumOut() == 2 && ft.Out(1) != errorType || ft.IsVariadic() {
+		panic("zinc: constructor must be a function returning a service and optionally an error")
+	}
+	deps := make([]reflect.Type, ft.NumIn())
+	for i := range deps {
+		deps[i] = ft.In(i)
+	}
+	a.container.add(ft.Out(0), &service{lifetime: lifetime, ctor: ctor, deps: deps})
+}
+
+// CheckServices reports services with missing dependencies, dependency
+// cycles and singletons that depend on scoped services or the request.
+func (a *App) CheckServices() error {
+	return a.container.check()
+}
+
+// Resolve returns the service of type T. It panics if none is provided
+// or it cannot be built.
+func Resolve[T any](c *Context) T {
+	svc, err := resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return svc
+}
+
+// TryResolve returns the service of type T, reporting false if none is
+// provided or it cannot be built.
+func TryResolve[T any](c *Context) (T, bool) {
+	svc, err := resolve[T](c)
+	return svc, err == nil
+}
+
+func resolve[T any](c *Context) (T, error) {
+	var zero T
+	t := reflect.TypeFor[T]()
+	if c.app == nil {
+		return zero, fmt.Errorf("zinc: service %v not provided", t)
+	}
+	v, err := c.app.container.resolve(t, c, nil)
+	if err != nil {
+		return zero, err
+	}
+	svc, _ := v.(T)
+	return svc, nil
+}
+
+// resolve returns the service of type t. c is nil while building
+// singletons, which cannot depend on the request. path holds the services
+// being built, to detect cycles.
+func (ct *container) resolve(t reflect.Type, c *Context, path []reflect.Type) (interface{}, error) {
+	if t == contextType {
+		if c == nil {
+			return nil, errors.New("zinc: *zinc.Context is not available to singletons")
+		}
+		return c, nil
+	}
+	s, ok := ct.lookup(t)
+	if !ok {
+		return nil, fmt.Errorf("zinc: service %v not provided", t)
+	}
+	if slices.Contains(path, t) {
+		return nil, fmt.Errorf("zinc: service dependency cycle: %s", cyclePath(append(path, t)))
+	}
+	path = append(path, t)
+
+	switch s.lifetime {
+	case Singleton:
+		s.once.Do(func() {
+			s.value, s.err = ct.build(s, nil, path)
+			if s.err == nil && closable(s.value) {
+				ct.closeMu.Lock()
+				ct.closers = append(ct.closers, s.value)
+				ct.closeMu.Unlock()
+			}
+		})
+		if s.err != nil {
+			return nil, fmt.Errorf("zinc: service %v: %w", t, s.err)
+		}
+		return s.value, nil
+
+	case Scoped:
+		if c == nil {
+			return nil, fmt.Errorf("zinc: scoped service %v is not available to singletons", t)
+		}
+		sc := c.serviceScope()
+		if sc.closed {
+			return nil, fmt.Errorf("zinc: scoped service %v resolved after its request completed", t)
+		}
+		if v, ok := sc.values[t]; ok {
+			return v, nil
+		}
+		v, err := ct.build(s, c, path)
+		if err != nil {
+			return nil, fmt.Errorf("zinc: service %v: %w", t, err)
+		}
+		sc.values[t] = v
+		if closable(v) {
+			sc.closers = append(sc.closers, v)
+		}
+		return v, nil
+
+	default:
+		v, err := ct.build(s, c, path)
+		if err != nil {
+			return nil, fmt.Errorf("zinc: service %v: %w", t, err)
+		}
+		if closable(v) {
+			if c != nil {
+				sc := c.serviceScope()
+				sc.closers = append(sc.closers, v)
+			} else {
+				ct.closeMu.Lock()
+				ct.closers = append(ct.closers, v)
+				ct.closeMu.Unlock()
+			}
+		}
+		return v, nil
+	}
+}
+
+// build calls the constructor of a service with its dependencies.
+func (ct *container) build(s *service, c *Context, path []reflect.Type) (interface{}, error) {
+	if s.build != nil {
+		return s.build()
+	}
+	if !s.ctor.IsValid() {
+		return s.value, nil
+	}
+	args := make([]reflect.Value, len(s.deps))
+	for i, dep := range s.deps {
+		v, err := ct.resolve(dep, c, path)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			args[i] = reflect.Zero(dep)
+		} else {
+			args[i] = reflect.ValueOf(v)
+		}
+	}
+	out := s.ctor.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	return out[0].Interface(), nil
+}
+
+// check validates the dependencies of every registered service.
+func (ct *container) check() error {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+
+	types := make([]reflect.Type, 0, len(ct.services))
+	for t := range ct.services {
+		types = append(types, t)
+	}
+	slices.SortFunc(types, func(a, b reflect.Type) int { return strings.Compare(a.String(), b.String()) })
+
+	var errs []error
+	for _, t := range types {
+		s := ct.services[t]
+		for _, dep := range s.deps {
+			if _, ok := ct.services[dep]; !ok && dep != contextType {
+				errs = append(errs, fmt.Errorf("zinc: service %v depends on %v, which is not provided", t, dep))
+			}
+		}
+		if s.lifetime == Singleton {
+			errs = append(errs, ct.checkSingleton(t, s)...)
+		}
+	}
+
+	// Report each cycle once, from the first service in it to be visited.
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[reflect.Type]int, len(types))
+	var visit func(t reflect.Type, path []reflect.Type)
+	visit = func(t reflect.Type, path []reflect.Type) {
+		switch state[t] {
+		case visiting:
+			i := slices.Index(path, t)
+			errs = append(errs, fmt.Errorf("zinc: service dependency cycle: %s", cyclePath(append(path[i:], t))))
+			return
+		case done:
+			return
+		}
+		state[t] = visiting
+		if s, ok := ct.services[t]; ok {
+			for _, dep := range s.deps {
+				visit(dep, append(path, t))
+			}
+		}
+		state[t] = done
+	}
+	for _, t := range types {
+		visit(t, nil)
+	}
+	return errors.Join(errs...)
+}
+
+// checkSingleton reports the scoped services and *Context that a singleton
+// depends on, directly or through the transient services built with it.
+func (ct *container) checkSingleton(t reflect.Type, s *service) []error {
+	var errs []error
+	seen := make(map[reflect.Type]bool)
+	var walk func(s *service, via []reflect.Type)
+	walk = func(s *service, via []reflect.Type) {
+		for _, dep := range s.deps {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+
+			through := ""
+			if len(via) > 0 {
+				through = " through transient " + cyclePath(via)
+			}
+			if dep == contextType {
+				errs = append(errs, fmt.Errorf("zinc: singleton %v depends on *zinc.Context%s", t, through))
+				continue
+			}
+			d, ok := ct.services[dep]
+			switch {
+			case !ok:
+			case d.lifetime == Scoped:
+				errs = append(errs, fmt.Errorf("zinc: singleton %v depends on scoped %v%s", t, dep, through))
+			case d.lifetime == Transient:
+				walk(d, append(via, dep))
+			}
+		}
+	}
+	walk(s, nil)
+	return errs
+}
+
+// close closes the singletons built by the container, in reverse build order.
+func (ct *container) close() error {
+	ct.closeMu.Lock()
+	closers := ct.closers
+	ct.closers = nil
+	ct.closeMu.Unlock()
+	return closeAll(closers)
+}
+
+// serviceScope returns the scope of the request, creating it if needed.
+func (c *Context) serviceScope() *scope {
+	if c.scope == nil {
+		c.scope = &scope{values: make(map[reflect.Type]interface{})}
+	}
+	return c.scope
+}
+
+// closeScope closes the services of the request, in reverse build order.
+func (c *Context) closeScope() error {
+	if c.scope == nil {
+		return nil
+	}
+	closers := c.scope.closers
+	c.scope.closers, c.scope.closed = nil, true
+	return closeAll(closers)
+}
+
+func closable(v interface{}) bool {
+	switch v.(type) {
+	case io.Closer, interface{ Close() }:
+		return true
+	}
+	return false
+}
+
+func closeAll(closers []interface{}) error {
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		switch v := closers[i].(type) {
+		case io.Closer:
+			errs = append(errs, v.Close())
+		case interface{ Close() }:
+			v.Close()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func cyclePath(path []reflect.Type) string {
+	names := make([]string, len(path))
+	for i, t := range path {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
+}
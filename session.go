@@ -0,0 +1,492 @@
+package zinc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SessionStore persists session data. Tokens identify a session and are kept
+// in an encrypted cookie; Save is called with an empty token for new sessions
+// and returns the token to store. Load returns nil data for missing or
+// expired sessions.
+type SessionStore interface {
+	Load(ctx context.Context, token string) ([]byte, error)
+	Save(ctx context.Context, token string, data []byte, ttl time.Duration) (string, error)
+	Delete(ctx context.Context, token string) error
+}
+
+// SessionConfig configures the Sessions middleware.
+type SessionConfig struct {
+	// Store persists sessions. Defaults to a MemoryStore.
+	Store SessionStore
+
+	// TTL is how long a session lives after it was last saved. Defaults to 24 hours.
+	TTL time.Duration
+
+	// Cookie is the template for the session cookie. The name defaults to "session".
+	Cookie Cookie
+}
+
+// ErrSessionTooLarge is returned by CookieStore when the session does not fit in a cookie.
+var ErrSessionTooLarge = errors.New("session too large for cookie")
+
+// sessionKey is the context store key of the current session.
+const sessionKey = "zinc.session"
+
+// Sessions returns middleware that loads the session from an encrypted
+// cookie and saves it before the response is sent. It requires
+// Config.CookieKeys; without them requests fail with a 500 error
+// wrapping ErrNoCookieKeys.
+// Errors saving the session once the response has started are logged
+// with Context.Logger, as the response can no longer report them.
+func Sessions(config ...SessionConfig) Middleware {
+	var cfg SessionConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.Cookie.Name == "" {
+		cfg.Cookie.Name = "session"
+	}
+
+	return func(c *Context) {
+		if len(c.cookieKeys()) == 0 {
+			c.Error(&HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError), Err: ErrNoCookieKeys})
+			return
+		}
+
+		s := &Session{}
+		if token, err := c.EncryptedCookie(cfg.Cookie.Name); err == nil {
+			data, err := cfg.Store.Load(c.Request.Context(), token)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			if data != nil && json.Unmarshal(data, &s.data) == nil {
+				s.token = token
+			}
+		}
+		c.Set(sessionKey, s)
+
+		// Headers cannot change once the response is written, so the
+		// session is saved just before, or after the chain if nothing was written.
+		c.writer.Before(func() {
+			if err := s.save(c, cfg); err != nil {
+				c.Logger().Error("saving session", "error", err)
+			}
+		})
+		c.Next()
+		if !c.Written() {
+			if err := s.save(c, cfg); err != nil {
+				c.Error(err)
+			}
+		}
+	}
+}
+
+// Session returns the session loaded by the Sessions middleware.
+// It panics if the middleware is not installed.
+func (c *Context) Session() *Session {
+	if s, ok := c.Get(sessionKey).(*Session); ok {
+		return s
+	}
+	panic("Session middleware not installed")
+}
+
+// Session holds the values of a client session. Values are stored as JSON,
+// so they are read back as the types encoding/json decodes into interface{}.
+type Session struct {
+	data        sessionData
+	token       string
+	changed     bool
+	regenerated bool
+	destroyed   bool
+	saved       bool
+}
+
+type sessionData struct {
+	Values  map[string]interface{}   `json:"values,omitempty"`
+	Flashes map[string][]interface{} `json:"flashes,omitempty"`
+}
+
+// Get returns a session value.
+func (s *Session) Get(key string) interface{} {
+	return s.data.Values[key]
+}
+
+// Set stores a session value.
+func (s *Session) Set(key string, value interface{}) {
+	if s.data.Values == nil {
+		s.data.Values = map[string]interface{}{}
+	}
+	s.data.Values[key] = value
+	s.changed = true
+}
+
+// Delete removes a session value.
+func (s *Session) Delete(key string) {
+	if _, ok := s.data.Values[key]; ok {
+		delete(s.data.Values, key)
+		s.changed = true
+	}
+}
+
+// Flash adds a value that is kept until it is read with Flashes,
+// typically on the next request.
+func (s *Session) Flash(key string, value interface{}) {
+	if s.data.Flashes == nil {
+		s.data.Flashes = map[string][]interface{}{}
+	}
+	s.data.Flashes[key] = append(s.data.Flashes[key], value)
+	s.changed = true
+}
+
+// Flashes returns and removes the flash values for key.
+func (s *Session) Flashes(key string) []interface{} {
+	values, ok := s.data.Flashes[key]
+	if ok {
+		delete(s.data.Flashes, key)
+		s.changed = true
+	}
+	return values
+}
+
+// Regenerate moves the session to a new token, keeping its values.
+// Call it after login to prevent session fixation.
+func (s *Session) Regenerate() {
+	s.regenerated = true
+	s.changed = true
+}
+
+// Destroy deletes the session and clears its cookie. Values set
+// afterwards, such as a flash message on logout, start a new session.
+func (s *Session) Destroy() {
+	s.data = sessionData{}
+	s.destroyed = true
+	s.changed = false
+}
+
+// save persists the session and sets or clears its cookie. It runs at most once.
+func (s *Session) save(c *Context, cfg SessionConfig) error {
+	if s.saved {
+		return nil
+	}
+	s.saved = true
+	ctx := c.Request.Context()
+
+	if s.destroyed || s.regenerated {
+		if s.token != "" {
+			if err := cfg.Store.Delete(ctx, s.token); err != nil {
+				return err
+			}
+			s.token = ""
+		}
+		if s.destroyed && !s.changed {
+			cookie := cfg.Cookie
+			cookie.MaxAge = -1
+			c.SetCookie(cookie)
+			return nil
+		}
+	}
+	if !s.changed {
+		return nil
+	}
+
+	data, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+	token, err := cfg.Store.Save(ctx, s.token, data, cfg.TTL)
+	if err != nil {
+		return err
+	}
+	s.token = token
+
+	cookie := cfg.Cookie
+	cookie.Value = token
+	cookie.MaxAge = int(cfg.TTL / time.Second)
+	return c.SetEncryptedCookie(cookie)
+}
+
+// newSessionToken returns a random 256-bit token.
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CookieStore keeps the whole session in the encrypted cookie.
+// Sessions must stay within the browser's 4KB cookie limit.
+type CookieStore struct{}
+
+// maxCookieSession leaves room for encryption and encoding overhead.
+const maxCookieSession = 2800
+
+func (CookieStore) Load(_ context.Context, token string) ([]byte, error) {
+	return []byte(token), nil
+}
+
+func (CookieStore) Save(_ context.Context, _ string, data []byte, _ time.Duration) (string, error) {
+	if len(data) > maxCookieSession {
+		return "", ErrSessionTooLarge
+	}
+	return string(data), nil
+}
+
+func (CookieStore) Delete(context.Context, string) error {
+	return nil
+}
+
+// MemoryStore keeps sessions in memory. Expired sessions are evicted
+// periodically as new sessions are saved.
+type MemoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+// sessionSweepInterval is how often stores evict expired sessions.
+const sessionSweepInterval = time.Minute
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memorySession), lastSweep: time.Now()}
+}
+
+func (m *MemoryStore) Load(_ context.Context, token string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[token]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(s.expires) {
+		delete(m.sessions, token)
+		return nil, nil
+	}
+	return s.data, nil
+}
+
+func (m *MemoryStore) Save(_ context.Context, token string, data []byte, ttl time.Duration) (string, error) {
+	if token == "" {
+		var err error
+		if token, err = newSessionToken(); err != nil {
+			return "", err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) > sessionSweepInterval {
+		for t, s := range m.sessions {
+			if now.After(s.expires) {
+				delete(m.sessions, t)
+			}
+		}
+		m.lastSweep = now
+	}
+	m.sessions[token] = memorySession{data: data, expires: now.Add(ttl)}
+	return token, nil
+}
+
+func (m *MemoryStore) Delete(_ context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, token)
+	return nil
+}
+
+// Len returns the number of stored sessions, including expired ones not yet evicted.
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
+
+// FileStore keeps each session in a file in a directory.
+// Expired files are removed periodically as new sessions are saved.
+type FileStore struct {
+	dir       string
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewFileStore creates a FileStore in dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, lastSweep: time.Now()}, nil
+}
+
+// path returns the file for a token. Tokens come from a verified cookie,
+// but are still checked so they can never name a file outside the directory.
+func (f *FileStore) path(token string) (string, bool) {
+	if b, err := base64.RawURLEncoding.DecodeString(token); err != nil || len(b) != 32 {
+		return "", false
+	}
+	return filepath.Join(f.dir, token), true
+}
+
+func (f *FileStore) Load(_ context.Context, token string) ([]byte, error) {
+	path, ok := f.path(token)
+	if !ok {
+		return nil, nil
+	}
+	data, expired, err := readSessionFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		os.Remove(path)
+		return nil, nil
+	}
+	return data, nil
+}
+
+func (f *FileStore) Save(_ context.Context, token string, data []byte, ttl time.Duration) (string, error) {
+	path, ok := f.path(token)
+	if !ok {
+		var err error
+		if token, err = newSessionToken(); err != nil {
+			return "", err
+		}
+		path, _ = f.path(token)
+	}
+	f.sweep()
+
+	// The file starts with the expiry time so that sweeps need not parse the session.
+	buf := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(buf, uint64(time.Now().Add(ttl).UnixNano()))
+	buf = append(buf, data...)
+
+	tmp, err := os.CreateTemp(f.dir, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (f *FileStore) Delete(_ context.Context, token string) error {
+	path, ok := f.path(token)
+	if !ok {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// sweep removes expired session files at most once per sessionSweepInterval.
+func (f *FileStore) sweep() {
+	f.mu.Lock()
+	if time.Since(f.lastSweep) < sessionSweepInterval {
+		f.mu.Unlock()
+		return
+	}
+	f.lastSweep = time.Now()
+	f.mu.Unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		path, ok := f.path(e.Name())
+		if !ok {
+			continue
+		}
+		if _, expired, err := readSessionFile(path); err == nil && expired {
+			os.Remove(path)
+		}
+	}
+}
+
+func readSessionFile(path string) (data []byte, expired bool, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(b) < 8 {
+		return nil, true, nil
+	}
+	expires := time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+	return b[8:], time.Now().After(expires), nil
+}
+
+// RedisClient is the subset of a Redis client used by RedisStore.
+// Get returns nil data and no error for missing keys.
+// Adapters for go-redis and similar clients take a few lines each.
+type RedisClient interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// RedisStore keeps sessions in Redis, relying on key expiry for eviction.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a RedisStore storing sessions under the given key prefix.
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (r *RedisStore) Load(ctx context.Context, token string) ([]byte, error) {
+	return r.client.Get(ctx, r.prefix+token)
+}
+
+func (r *RedisStore) Save(ctx context.Context, token string, data []byte, ttl time.Duration) (string, error) {
+	if token == "" {
+		var err error
+		if token, err = newSessionToken(); err != nil {
+			return "", err
+		}
+	}
+	return token, r.client.Set(ctx, r.prefix+token, data, ttl)
+}
+
+func (r *RedisStore) Delete(ctx context.Context, token string) error {
+	return r.client.Del(ctx, r.prefix+token)
+}
@@ -0,0 +1,315 @@
+package zinc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEWriter writes a server-sent event stream. Every write is flushed
+// immediately. It is safe for concurrent use, e.g. alongside Heartbeat.
+// The stream ends when the handler returns.
+type SSEWriter struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	ctx         context.Context
+	cfg         *Config
+	lastEventID string
+	closed      bool
+	done        chan struct{} // closed when the handler returns
+}
+
+// errSSEClosed is returned by writes after the handler has returned.
+var errSSEClosed = errors.New("zinc: event stream closed")
+
+// SSEEvent is an event sent through an SSEHub.
+type SSEEvent struct {
+	Event string
+	ID    string
+	Data  interface{}
+}
+
+// SSE starts a server-sent event stream, sending the event stream headers.
+func (c *Context) SSE() (*SSEWriter, error) {
+	if c.Written() {
+		return nil, ErrResponseAlreadySent
+	}
+	c.written = true
+
+	header := c.Response.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.Response.WriteHeader(c.status)
+
+	// Write to the underlying response rather than the Context's writer,
+	// which is reused once the handler returns.
+	w := c.Response
+	if w == http.ResponseWriter(&c.writer) {
+		w = c.writer.ResponseWriter
+	}
+	s := &SSEWriter{
+		w:           w,
+		rc:          http.NewResponseController(w),
+		ctx:         c.Request.Context(),
+		cfg:         c.config(),
+		lastEventID: c.Request.Header.Get("Last-Event-ID"),
+		done:        make(chan struct{}),
+	}
+	c.onRelease(s.close)
+	return s, s.flush()
+}
+
+// LastEventID returns the Last-Event-ID sent by a reconnecting client.
+func (s *SSEWriter) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done is closed when the client disconnects.
+func (s *SSEWriter) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send writes an event. The event name and ID may be empty.
+// Strings and byte slices are sent as is; other data is encoded as JSON.
+// Multi-line data is split across data fields.
+func (s *SSEWriter) Send(event, id string, data interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if event != "" {
+		buf.WriteString("event: " + sseField(event) + "\n")
+	}
+	if id != "" {
+		buf.WriteString("id: " + sseField(id) + "\n")
+	}
+
+	var payload []byte
+	switch v := data.(type) {
+	case string:
+		payload = []byte(v)
+	case []byte:
+		payload = v
+	default:
+		var encoded bytes.Buffer
+		if err := s.cfg.encodeJSON(&encoded, v); err != nil {
+			return err
+		}
+		payload = bytes.TrimRight(encoded.Bytes(), "\n")
+	}
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	return s.write(buf.Bytes())
+}
+
+// Retry tells the client how long to wait before reconnecting.
+func (s *SSEWriter) Retry(d time.Duration) error {
+	return s.write([]byte("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n"))
+}
+
+// Comment writes a comment line, which clients ignore.
+func (s *SSEWriter) Comment(text string) error {
+	return s.write([]byte(": " + sseField(text) + "\n\n"))
+}
+
+// Heartbeat writes a comment at the given interval to keep the connection
+// open through proxies. It stops when the client disconnects, a write fails,
+// the handler returns or the returned function is called.
+func (s *SSEWriter) Heartbeat(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	quit := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if s.Comment("heartbeat") != nil {
+					return
+				}
+			case <-s.ctx.Done():
+				return
+			case <-s.done:
+				return
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(quit) })
+	}
+}
+
+func (s *SSEWriter) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errSSEClosed
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// close ends the stream, stopping its heartbeats and refusing later writes.
+func (s *SSEWriter) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+func (s *SSEWriter) flush() error {
+	if err := s.rc.Flush(); err != nil && err != http.ErrNotSupported {
+		return err
+	}
+	return nil
+}
+
+// sseField strips line breaks, which would end a field early.
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(s)
+}
+
+// SSEHub fans out events to many subscribers. It keeps a bounded history
+// so reconnecting clients can resume from their Last-Event-ID.
+type SSEHub struct {
+	mu          sync.Mutex
+	subscribers map[chan SSEEvent]struct{}
+	history     []SSEEvent
+	size        int
+	nextID      uint64
+}
+
+// sseSubscriberBuffer is the number of events queued per subscriber.
+// Subscribers that fall further behind are disconnected and can resume.
+const sseSubscriberBuffer = 16
+
+// NewSSEHub creates a hub that keeps the given number of recent events for resumption.
+func NewSSEHub(history int) *SSEHub {
+	return &SSEHub{
+		subscribers: make(map[chan SSEEvent]struct{}),
+		size:        history,
+	}
+}
+
+// Publish sends an event to every subscriber. Events without an ID are
+// given a sequential one so that clients can resume after them.
+func (h *SSEHub) Publish(ev SSEEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	if ev.ID == "" {
+		ev.ID = strconv.FormatUint(h.nextID, 10)
+	}
+
+	if h.size > 0 {
+		if len(h.history) == h.size {
+			h.history = append(h.history[:0], h.history[1:]...)
+		}
+		h.history = append(h.history, ev)
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber, returning its event channel, the events
+// published after lastEventID that are still in the history, and a function
+// to unsubscribe. The channel is closed if the subscriber falls behind.
+func (h *SSEHub) Subscribe(lastEventID string) (<-chan SSEEvent, []SSEEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var missed []SSEEvent
+	if lastEventID != "" {
+		for i, ev := range h.history {
+			if ev.ID == lastEventID {
+				missed = append(missed, h.history[i+1:]...)
+				break
+			}
+		}
+	}
+
+	ch := make(chan SSEEvent, sseSubscriberBuffer)
+	h.subscribers[ch] = struct{}{}
+
+	return ch, missed, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Serve streams the hub's events to the client until it disconnects,
+// first replaying any events missed since its Last-Event-ID.
+// It can be registered directly as a route handler.
+func (h *SSEHub) Serve(c *Context) {
+	s, err := c.SSE()
+	if err != nil {
+		return
+	}
+
+	events, missed, unsubscribe := h.Subscribe(s.LastEventID())
+	defer unsubscribe()
+
+	for _, ev := range missed {
+		if s.Send(ev.Event, ev.ID, ev.Data) != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok || s.Send(ev.Event, ev.ID, ev.Data) != nil {
+				return
+			}
+		case <-s.Done():
+			return
+		}
+	}
+}
+
+// Subscribers returns the number of connected subscribers.
+func (h *SSEHub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
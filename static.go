@@ -0,0 +1,301 @@
+package zinc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticOptions configures App.StaticDir.
+type StaticOptions struct {
+	// Index is the file served for directory requests. Defaults to "index.html".
+	Index string
+
+	// Browse lists the contents of directories without an index file.
+	Browse bool
+
+	// SPA serves the root index file for missing paths requested by a
+	// browser navigation (an Accept header listing text/html), so that a
+	// single-page application can handle its own routes.
+	SPA bool
+
+	// Precompressed serves a .br or .gz sibling of a file, when it exists
+	// and the client accepts that encoding.
+	Precompressed bool
+
+	// MaxAge sets the Cache-Control max-age of files without a content hash
+	// in their name. By default clients revalidate them on every use.
+	MaxAge time.Duration
+
+	// Hashed reports whether a file name contains a content hash, in which
+	// case it is cached as immutable. Defaults to detecting names such as
+	// app.3f9a8c2d.js and index-BQ3x9aZk.js.
+	Hashed func(name string) bool
+}
+
+// StaticDir serves the files of fsys under prefix. It works with embed.FS,
+// os.DirFS and any other fs.FS. Responses carry an ETag and, when the file
+// system records modification times, Last-Modified, and conditional and
+// Range requests are honoured. Names starting with a dot are not served.
+func (a *App) StaticDir(prefix string, fsys fs.FS, opts ...StaticOptions) {
+	var o StaticOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Index == "" {
+		o.Index = "index.html"
+	}
+	if o.Hashed == nil {
+		o.Hashed = hashedName
+	}
+	s := &staticServer{fsys: fsys, opts: o}
+
+	prefix = "/" + strings.Trim(prefix, "/")
+	for _, method := range []string{MethodGet, MethodHead} {
+		a.router.Add(method, prefix, RouteDoc{Hidden: true}, s.serve)
+		a.router.Add(method, strings.TrimSuffix(prefix, "/")+"/*", RouteDoc{Hidden: true}, s.serve)
+	}
+}
+
+type staticServer struct {
+	fsys  fs.FS
+	opts  StaticOptions
+	etags sync.Map // name -> staticETag
+}
+
+type staticETag struct {
+	modtime time.Time
+	size    int64
+	etag    string
+}
+
+func (s *staticServer) serve(c *Context) {
+	name := strings.TrimPrefix(path.Clean("/"+c.Param("*")), "/")
+	if name == "" {
+		name = "."
+	}
+	if hiddenPath(name) {
+		s.notFound(c)
+		return
+	}
+
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		s.notFound(c)
+		return
+	}
+	if !info.IsDir() {
+		s.serveFile(c, name, info)
+		return
+	}
+
+	// Redirect to the slash form so that relative links in the page resolve.
+	if p := c.Request.URL.Path; !strings.HasSuffix(p, "/") {
+		target := p + "/"
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, target)
+		return
+	}
+	index := path.Join(name, s.opts.Index)
+	if info, err := fs.Stat(s.fsys, index); err == nil && !info.IsDir() {
+		s.serveFile(c, index, info)
+		return
+	}
+	if s.opts.Browse {
+		s.list(c, name)
+		return
+	}
+	s.notFound(c)
+}
+
+// notFound serves the SPA index to browser navigations, or responds with 404.
+func (s *staticServer) notFound(c *Context) {
+	if s.opts.SPA && strings.Contains(c.Request.Header.Get("Accept"), "text/html") {
+		if info, err := fs.Stat(s.fsys, s.opts.Index); err == nil && !info.IsDir() {
+			s.serveFile(c, s.opts.Index, info)
+			return
+		}
+	}
+	c.Error(ErrFileNotFound)
+}
+
+func (s *staticServer) serveFile(c *Context, name string, info fs.FileInfo) {
+	header := c.Response.Header()
+	header.Set("Cache-Control", s.cacheControl(name))
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+
+	served := name
+	if s.opts.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		for _, enc := range [...]struct{ ext, coding string }{{".br", "br"}, {".gz", "gzip"}} {
+			if !acceptsEncoding(c.Request.Header.Get("Accept-Encoding"), enc.coding) {
+				continue
+			}
+			if st, err := fs.Stat(s.fsys, name+enc.ext); err == nil && !st.IsDir() {
+				served, info = name+enc.ext, st
+				header.Set("Content-Encoding", enc.coding)
+				break
+			}
+		}
+	}
+
+	f, err := s.fsys.Open(served)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+
+	etag, err := s.etag(served, info, content)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	header.Set("ETag", etag)
+	c.ServeContent(name, info.ModTime(), content)
+}
+
+// etag returns a strong ETag from the file's content hash, cached until
+// the file's size or modification time changes.
+func (s *staticServer) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if v, ok := s.etags.Load(name); ok {
+		if e := v.(staticETag); e.size == info.Size() && e.modtime.Equal(info.ModTime()) {
+			return e.etag, nil
+		}
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.etags.Store(name, staticETag{modtime: info.ModTime(), size: info.Size(), etag: etag})
+	return etag, nil
+}
+
+func (s *staticServer) cacheControl(name string) string {
+	switch {
+	case s.opts.Hashed(path.Base(name)):
+		return "public, max-age=31536000, immutable"
+	case s.opts.MaxAge > 0:
+		return "public, max-age=" + strconv.Itoa(int(s.opts.MaxAge/time.Second))
+	}
+	return "no-cache"
+}
+
+// list writes an HTML listing of a directory.
+func (s *staticServer) list(c *Context, dir string) {
+	entries, err := fs.ReadDir(s.fsys, dir)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var b strings.Builder
+	title := html.EscapeString(c.Request.URL.Path)
+	b.WriteString("<!doctype html>\n<meta charset=\"utf-8\">\n<title>" + title + "</title>\n<h1>" + title + "</h1>\n<ul>\n")
+	if dir != "." {
+		b.WriteString("<li><a href=\"../\">../</a></li>\n")
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		}
+		href := (&url.URL{Path: name}).String()
+		b.WriteString("<li><a href=\"" + html.EscapeString(href) + "\">" + html.EscapeString(name) + "</a></li>\n")
+	}
+	b.WriteString("</ul>\n")
+
+	c.Response.Header().Set("Cache-Control", "no-cache")
+	c.HTML(b.String())
+}
+
+// hiddenPath reports whether any element of a slash-separated path starts with a dot.
+func hiddenPath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part != "." && strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// hashedName reports whether a file name has a segment that looks like a
+// content hash, as produced by common bundlers: at least 8 letters and
+// digits, mixed, between '.' or '-' separators.
+func hashedName(name string) bool {
+	segments := strings.FieldsFunc(strings.TrimSuffix(name, path.Ext(name)), func(r rune) bool {
+		return r == '.' || r == '-'
+	})
+	// The first segment is the base name, never the hash.
+	for _, seg := range segments[min(1, len(segments)):] {
+		if len(seg) < 8 {
+			continue
+		}
+		var digits, letters, other int
+		for _, r := range seg {
+			switch {
+			case r >= '0' && r <= '9':
+				digits++
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+				letters++
+			default:
+				other++
+			}
+		}
+		if other == 0 && digits > 0 && letters > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows coding.
+func acceptsEncoding(header, coding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, coding) && name != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
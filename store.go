@@ -0,0 +1,36 @@
+package zinc
+
+// Key is a typed key for values in Context.Store. Keys with the same name
+// refer to the same value, so names should be unique within an application.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a key for values of type T stored under name.
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+// Name returns the name the key stores its value under.
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// SetValue stores a value in the context store.
+func SetValue[T any](c *Context, key Key[T], value T) {
+	c.Set(key.name, value)
+}
+
+// Value returns the value stored for key, or the zero value of T
+// when none is stored or the stored value is not a T.
+func Value[T any](c *Context, key Key[T]) T {
+	v, _ := TryValue(c, key)
+	return v
+}
+
+// TryValue returns the value stored for key, reporting whether
+// a value of type T was found.
+func TryValue[T any](c *Context, key Key[T]) (T, bool) {
+	v, ok := c.Get(key.name).(T)
+	return v, ok
+}
@@ -0,0 +1,312 @@
+package zinc
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrUploadTooLarge is returned when a multipart body or one of its files exceeds its limit.
+	ErrUploadTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge)
+	// ErrTooManyUploads is returned when a multipart body has more files than allowed.
+	ErrTooManyUploads = NewHTTPError(http.StatusRequestEntityTooLarge, "too many files")
+	// ErrUploadType is returned when the sniffed type of a file is not allowed.
+	ErrUploadType = NewHTTPError(http.StatusUnsupportedMediaType, "file type not allowed")
+)
+
+// UploadLimits restricts the multipart bodies read by Context.MultipartForm,
+// FormFile, FormValue and Uploads. Zero values mean no limit.
+type UploadLimits struct {
+	// MaxSize limits the size of the whole body.
+	MaxSize int64
+
+	// MaxFiles limits the number of files.
+	MaxFiles int
+
+	// MaxFileSize limits the size of each file.
+	MaxFileSize int64
+
+	// AllowedTypes lists the media types files may have, such as "image/png"
+	// or "image/*". Types are sniffed from the content, not taken from the client.
+	AllowedTypes []string
+
+	// Memory is the memory used by MultipartForm before files are written to
+	// temporary files, and the limit on form fields read by Uploads. Defaults to 32MB.
+	Memory int64
+}
+
+func (l UploadLimits) memory() int64 {
+	if l.Memory <= 0 {
+		return defaultMultipartMemory
+	}
+	return l.Memory
+}
+
+// FormValue returns the first value of a form field from the body or the
+// query string, parsing the body within the configured upload limits.
+// It returns "" for every field if the body cannot be parsed or exceeds the
+// limits; the error is dropped, and is returned by MultipartForm and Bind.
+func (c *Context) FormValue(name string) string {
+	if c.parseForm() != nil {
+		return ""
+	}
+	return c.Request.FormValue(name)
+}
+
+// FormFile returns the first file uploaded in a multipart form field.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	if files := form.File[name]; len(files) > 0 {
+		return files[0], nil
+	}
+	return nil, http.ErrMissingFile
+}
+
+// MultipartForm parses a multipart body within Config.Uploads. Files beyond
+// the memory limit are written to temporary files, which are removed when
+// the request is complete. If the body cannot be parsed or exceeds the
+// limits, the same error is returned by every later call.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.formErr != nil {
+		return nil, c.formErr
+	}
+	r := c.Request
+	if r.MultipartForm != nil {
+		return r.MultipartForm, nil
+	}
+
+	limits := c.config().Uploads
+	if limits.MaxSize > 0 {
+		r.Body = http.MaxBytesReader(c.Response, r.Body, limits.MaxSize)
+	}
+	if err := r.ParseMultipartForm(limits.memory()); err != nil {
+		return nil, c.rejectForm(uploadError(err))
+	}
+	if err := limits.check(r.MultipartForm); err != nil {
+		return nil, c.rejectForm(err)
+	}
+	return r.MultipartForm, nil
+}
+
+// rejectForm discards a form that could not be parsed within the limits,
+// so that its values cannot be read through the request, and records err.
+func (c *Context) rejectForm(err error) error {
+	r := c.Request
+	if r.MultipartForm != nil {
+		r.MultipartForm.RemoveAll()
+	}
+	r.MultipartForm, r.Form, r.PostForm = nil, nil, nil
+	c.formErr = err
+	return err
+}
+
+// parseForm parses the request body, if not already done.
+func (c *Context) parseForm() error {
+	if c.formErr != nil {
+		return c.formErr
+	}
+	if c.Request.Form != nil {
+		return nil
+	}
+	if c.mediaType() == "multipart/form-data" {
+		_, err := c.MultipartForm()
+		return err
+	}
+	return c.Request.ParseForm()
+}
+
+// check applies the file limits to a parsed form.
+func (l UploadLimits) check(form *multipart.Form) error {
+	files := 0
+	for _, headers := range form.File {
+		for _, fh := range headers {
+			files++
+			if l.MaxFiles > 0 && files > l.MaxFiles {
+				return ErrTooManyUploads
+			}
+			if l.MaxFileSize > 0 && fh.Size > l.MaxFileSize {
+				return ErrUploadTooLarge
+			}
+			if len(l.AllowedTypes) == 0 {
+				continue
+			}
+			contentType, err := DetectFileType(fh)
+			if err != nil {
+				return err
+			}
+			if !typeAllowed(contentType, l.AllowedTypes) {
+				return ErrUploadType
+			}
+		}
+	}
+	return nil
+}
+
+// DetectFileType sniffs the media type of an uploaded file from its first
+// 512 bytes with http.DetectContentType.
+func DetectFileType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// Upload is a file read from a multipart body by Context.Uploads.
+type Upload struct {
+	FieldName string
+	FileName  string
+	Header    textproto.MIMEHeader
+
+	// ContentType is sniffed from the content; the type sent by the client is in Header.
+	ContentType string
+
+	r     *bufio.Reader
+	size  int64
+	limit int64
+}
+
+// Read reads the file content, failing with ErrUploadTooLarge
+// once the file exceeds UploadLimits.MaxFileSize.
+func (u *Upload) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.size += int64(n)
+	if u.limit > 0 && u.size > u.limit {
+		return n, ErrUploadTooLarge
+	}
+	return n, err
+}
+
+// Size returns the number of bytes read so far.
+func (u *Upload) Size() int64 {
+	return u.size
+}
+
+// Uploads streams a multipart body, calling fn for each file as it arrives
+// without buffering it in memory or on disk. Form fields are collected and
+// available through FormValue once it returns. Limits default to Config.Uploads.
+// Any part of a file that fn does not read is discarded.
+func (c *Context) Uploads(fn func(u *Upload) error, limits ...UploadLimits) error {
+	l := c.config().Uploads
+	if len(limits) > 0 {
+		l = limits[0]
+	}
+
+	r := c.Request
+	if c.mediaType() != "multipart/form-data" {
+		return ErrUnsupportedMediaType
+	}
+	if l.MaxSize > 0 {
+		r.Body = http.MaxBytesReader(c.Response, r.Body, l.MaxSize)
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return uploadError(err)
+	}
+
+	form := url.Values{}
+	fieldBytes, files := int64(0), 0
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return uploadError(err)
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, l.memory()-fieldBytes+1))
+			if err != nil {
+				return uploadError(err)
+			}
+			if fieldBytes += int64(len(value)); fieldBytes > l.memory() {
+				return ErrUploadTooLarge
+			}
+			form.Add(part.FormName(), string(value))
+			continue
+		}
+
+		if files++; l.MaxFiles > 0 && files > l.MaxFiles {
+			return ErrTooManyUploads
+		}
+		u := &Upload{
+			FieldName: part.FormName(),
+			FileName:  part.FileName(),
+			Header:    part.Header,
+			r:         bufio.NewReaderSize(part, 512),
+			limit:     l.MaxFileSize,
+		}
+		head, err := u.r.Peek(512)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return uploadError(err)
+		}
+		u.ContentType = http.DetectContentType(head)
+		if len(l.AllowedTypes) > 0 && !typeAllowed(u.ContentType, l.AllowedTypes) {
+			return ErrUploadType
+		}
+
+		if err := fn(u); err != nil {
+			return uploadError(err)
+		}
+		if _, err := io.Copy(io.Discard, u); err != nil {
+			return uploadError(err)
+		}
+	}
+
+	// Body values come before query values, as with http.Request.ParseForm.
+	r.PostForm = form
+	r.Form = make(url.Values, len(form))
+	for k, v := range form {
+		r.Form[k] = append(r.Form[k], v...)
+	}
+	for k, v := range r.URL.Query() {
+		r.Form[k] = append(r.Form[k], v...)
+	}
+	return nil
+}
+
+// uploadError reports bodies over http.MaxBytesReader's limit as ErrUploadTooLarge
+// and non-multipart bodies as ErrUnsupportedMediaType.
+func uploadError(err error) error {
+	var mbe *http.MaxBytesError
+	switch {
+	case errors.As(err, &mbe):
+		return ErrUploadTooLarge
+	case errors.Is(err, http.ErrNotMultipart):
+		return ErrUnsupportedMediaType
+	}
+	return err
+}
+
+// typeAllowed reports whether a media type matches one of the allowed types,
+// which may end in "/*".
+func typeAllowed(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if a == mediaType || strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, a[:len(a)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,417 @@
+package zinc
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Validator validates a decoded or bound value.
+// Implementations return nil when the value is valid.
+type Validator interface {
+	Validate(v interface{}) error
+}
+
+// ValidatorFunc adapts a function to the Validator interface,
+// e.g. zinc.ValidatorFunc(validator.New().Struct) for go-playground/validator.
+type ValidatorFunc func(v interface{}) error
+
+// Validate calls f(v).
+func (f ValidatorFunc) Validate(v interface{}) error {
+	return f(v)
+}
+
+// DefaultValidator validates structs using `validate` tags.
+// Supported rules are required, omitempty, min, max, len, gt, gte, lt, lte,
+// email, url, uuid, oneof, regex and dive. Nested structs are always validated;
+// slice, array and map elements are validated after dive. Invalid tags are
+// reported as 500 Internal Server Error.
+var DefaultValidator Validator = tagValidator{}
+
+// FieldError describes a single field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors lists every field that failed validation.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// StatusCode returns 422 Unprocessable Entity.
+func (e ValidationErrors) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+type validationRule struct {
+	name  string
+	param string
+	check func(v reflect.Value, param string) bool
+}
+
+type validationField struct {
+	index     int
+	name      string
+	omitempty bool
+	required  bool
+	rules     []validationRule
+	dive      *validationField
+}
+
+var (
+	validationCache sync.Map
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	regexCache      sync.Map
+)
+
+type tagValidator struct{}
+
+// Validate reports invalid `validate` tags as 500 Internal Server Error,
+// as they are programming errors.
+func (tagValidator) Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if err := validateStruct(rv, "", &errs); err != nil {
+		return &HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError), Err: err}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, namespace string, errs *ValidationErrors) error {
+	fields, err := cachedValidationFields(v.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if err := validateValue(v.Field(f.index), &f, namespace+f.name, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, f *validationField, name string, errs *ValidationErrors) error {
+	if isEmptyValue(v) {
+		if f.required {
+			*errs = append(*errs, &FieldError{Field: name, Rule: "required", Message: "is required"})
+		}
+		if f.required || f.omitempty || v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			return nil
+		}
+	}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	for _, rule := range f.rules {
+		if !rule.check(v, rule.param) {
+			*errs = append(*errs, &FieldError{
+				Field:   name,
+				Rule:    rule.name,
+				Param:   rule.param,
+				Message: validationMessage(v, rule),
+			})
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() != timeType {
+			return validateStruct(v, name+".", errs)
+		}
+	case reflect.Slice, reflect.Array:
+		if f.dive != nil {
+			for i := 0; i < v.Len(); i++ {
+				if err := validateValue(v.Index(i), f.dive, name+"["+strconv.Itoa(i)+"]", errs); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		if f.dive != nil {
+			iter := v.MapRange()
+			for iter.Next() {
+				if err := validateValue(iter.Value(), f.dive, fmt.Sprintf("%s[%v]", name, iter.Key()), errs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// validationEntry is a cached result of parsing the tags of a struct type.
+type validationEntry struct {
+	fields []validationField
+	err    error
+}
+
+// cachedValidationFields returns the validation rules of a struct type.
+func cachedValidationFields(t reflect.Type) ([]validationField, error) {
+	if e, ok := validationCache.Load(t); ok {
+		e := e.(*validationEntry)
+		return e.fields, e.err
+	}
+
+	e := &validationEntry{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		f, err := parseValidationTag(tag)
+		if err != nil {
+			e.fields, e.err = nil, fmt.Errorf("zinc: field %s.%s: %w", t, sf.Name, err)
+			break
+		}
+		f.index = i
+		f.name = fieldName(sf)
+		e.fields = append(e.fields, *f)
+	}
+
+	validationCache.Store(t, e)
+	return e.fields, e.err
+}
+
+// checkValidationTags parses the `validate` tags of t and of the struct
+// types it contains, reporting the first invalid one.
+func checkValidationTags(t reflect.Type) error {
+	return walkValidationTags(t, make(map[reflect.Type]bool))
+}
+
+func walkValidationTags(t reflect.Type, seen map[reflect.Type]bool) error {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+	if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	if _, err := cachedValidationFields(t); err != nil {
+		return err
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.IsExported() {
+			if err := walkValidationTags(sf.Type, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldName returns the JSON name of a field, falling back to its Go name.
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+// parseValidationTag parses a comma separated list of rules.
+// A regex rule consumes the remainder of the tag so patterns may contain commas.
+func parseValidationTag(tag string) (*validationField, error) {
+	f := &validationField{}
+	current := f
+	for tag != "" {
+		var token string
+		if strings.HasPrefix(tag, "regex=") {
+			token, tag = tag, ""
+		} else {
+			token, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(token), "=")
+		switch name {
+		case "":
+		case "required":
+			current.required = true
+		case "omitempty":
+			current.omitempty = true
+		case "dive":
+			current.dive = &validationField{}
+			current = current.dive
+		default:
+			check, ok := validationRules[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown validation rule %q", name)
+			}
+			switch name {
+			case "min", "gte", "max", "lte", "gt", "lt", "len":
+				if _, err := strconv.ParseFloat(param, 64); err != nil {
+					return nil, fmt.Errorf("invalid parameter %q for validation rule %q", param, name)
+				}
+			case "regex":
+				re, err := regexp.Compile(param)
+				if err != nil {
+					return nil, fmt.Errorf("invalid validation regex: %w", err)
+				}
+				regexCache.LoadOrStore(param, re)
+			}
+			current.rules = append(current.rules, validationRule{name: name, param: param, check: check})
+		}
+	}
+	return f, nil
+}
+
+var validationRules = map[string]func(v reflect.Value, param string) bool{
+	"min":   func(v reflect.Value, p string) bool { return compareSize(v, p) >= 0 },
+	"gte":   func(v reflect.Value, p string) bool { return compareSize(v, p) >= 0 },
+	"max":   func(v reflect.Value, p string) bool { return compareSize(v, p) <= 0 },
+	"lte":   func(v reflect.Value, p string) bool { return compareSize(v, p) <= 0 },
+	"gt":    func(v reflect.Value, p string) bool { return compareSize(v, p) > 0 },
+	"lt":    func(v reflect.Value, p string) bool { return compareSize(v, p) < 0 },
+	"len":   func(v reflect.Value, p string) bool { return compareSize(v, p) == 0 },
+	"email": validateEmail,
+	"url":   validateURL,
+	"uuid": func(v reflect.Value, _ string) bool {
+		return v.Kind() == reflect.String && uuidPattern.MatchString(v.String())
+	},
+	"oneof": func(v reflect.Value, p string) bool {
+		s := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(p) {
+			if s == option {
+				return true
+			}
+		}
+		return false
+	},
+	"regex": func(v reflect.Value, p string) bool {
+		re, _ := regexCache.Load(p)
+		return v.Kind() == reflect.String && re.(*regexp.Regexp).MatchString(v.String())
+	},
+}
+
+// compareSize compares a value with a numeric parameter, which
+// parseValidationTag has checked. Strings are compared by rune count
+// and collections by length.
+func compareSize(v reflect.Value, param string) int {
+	limit, _ := strconv.ParseFloat(param, 64)
+
+	var n float64
+	switch v.Kind() {
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(v.String()))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n = float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	}
+
+	switch {
+	case n < limit:
+		return -1
+	case n > limit:
+		return 1
+	}
+	return 0
+}
+
+func validateEmail(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(v.String())
+	return err == nil && addr.Address == v.String()
+}
+
+func validateURL(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.ParseRequestURI(v.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func validationMessage(v reflect.Value, rule validationRule) string {
+	unit := ""
+	switch v.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch rule.name {
+	case "min", "gte":
+		return "must be at least " + rule.param + unit
+	case "max", "lte":
+		return "must be at most " + rule.param + unit
+	case "gt":
+		return "must be greater than " + rule.param + unit
+	case "lt":
+		return "must be less than " + rule.param + unit
+	case "len":
+		return "must be exactly " + rule.param + unit
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "uuid":
+		return "must be a valid UUID"
+	case "oneof":
+		return "must be one of [" + rule.param + "]"
+	case "regex":
+		return "must match " + rule.param
+	}
+	return "failed the " + rule.name + " rule"
+}
@@ -0,0 +1,939 @@
+package zinc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket message types.
+const (
+	WSText   = 1
+	WSBinary = 2
+	WSClose  = 8
+	WSPing   = 9
+	WSPong   = 10
+
+	wsContinuation = 0
+)
+
+// WebSocket close codes (RFC 6455 section 7.4.1).
+const (
+	WSCloseNormal             = 1000
+	WSCloseGoingAway          = 1001
+	WSCloseProtocolError      = 1002
+	WSCloseUnsupportedData    = 1003
+	WSCloseNoStatus           = 1005
+	WSCloseAbnormal           = 1006
+	WSCloseInvalidPayload     = 1007
+	WSClosePolicyViolation    = 1008
+	WSCloseMessageTooBig      = 1009
+	WSCloseMandatoryExtension = 1010
+	WSCloseInternalError      = 1011
+)
+
+const (
+	wsAcceptGUID        = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsDeflateExtension  = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+	wsMaxControlPayload = 125
+	wsDefaultReadLimit  = 1 << 20
+	wsDefaultFrameSize  = 4 << 10
+)
+
+// wsDeflateTail restores the sync flush marker stripped by the sender
+// and ends the stream with an empty final block.
+var wsDeflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+var (
+	// ErrWSClosed is returned when writing to a connection after its close frame was sent.
+	ErrWSClosed = errors.New("websocket: connection closed")
+	// ErrWSBadHandshake is returned by DialWebSocket when the server refuses the upgrade.
+	ErrWSBadHandshake = errors.New("websocket: bad handshake")
+)
+
+// WSCloseError is returned by ReadMessage when the connection is closed,
+// either by the peer or because the peer violated the protocol.
+type WSCloseError struct {
+	Code int
+	Text string
+}
+
+func (e *WSCloseError) Error() string {
+	if e.Text == "" {
+		return "websocket: close " + strconv.Itoa(e.Code)
+	}
+	return "websocket: close " + strconv.Itoa(e.Code) + ": " + e.Text
+}
+
+// WSConfig configures a WebSocket route, or a client in DialWebSocket.
+// Pass it alongside the handler.
+type WSConfig struct {
+	// Subprotocols lists the supported subprotocols in order of preference.
+	Subprotocols []string
+	// CheckOrigin reports whether the request origin is allowed.
+	// By default, cross-origin requests are refused.
+	CheckOrigin func(r *http.Request) bool
+	// EnableCompression negotiates permessage-deflate.
+	EnableCompression bool
+	// ReadLimit is the maximum message size in bytes. Defaults to 1MB.
+	ReadLimit int64
+	// FrameSize is the maximum payload of outgoing data frames;
+	// larger messages are fragmented. Defaults to 4KB.
+	FrameSize int
+	// PingInterval sends pings at the given interval and fails reads when
+	// nothing is received for twice the interval. Zero disables keepalive.
+	PingInterval time.Duration
+}
+
+// WebSocket registers a WebSocket endpoint. The last handler receives the
+// upgraded connection; handlers before it run as route middleware, so
+// authentication and path params work as they do for other routes.
+// A WSConfig may be passed alongside the handlers.
+func (a *App) WebSocket(path string, handlers ...interface{}) {
+	a.Get(path, wsHandlers(handlers)...)
+}
+
+// WebSocket registers a WebSocket endpoint in the group.
+func (g *Group) WebSocket(path string, handlers ...interface{}) {
+	g.Get(path, wsHandlers(handlers)...)
+}
+
+// wsHandlers replaces WebSocket handlers with upgrade handlers using the
+// route's WSConfig. WebSocket routes are hidden from OpenAPI documents.
+func wsHandlers(handlers []interface{}) []interface{} {
+	var cfg WSConfig
+	for _, h := range handlers {
+		if c, ok := h.(WSConfig); ok {
+			cfg = c
+		}
+	}
+
+	all := make([]interface{}, 0, len(handlers)+1)
+	all = append(all, RouteDoc{Hidden: true})
+	for _, h := range handlers {
+		switch v := h.(type) {
+		case WSConfig:
+		case func(*WSConn):
+			all = append(all, cfg.handler(v))
+		default:
+			all = append(all, h)
+		}
+	}
+	return all
+}
+
+// handler upgrades the request and runs fn, closing the connection when it returns.
+func (cfg WSConfig) handler(fn func(*WSConn)) RouteHandler {
+	return func(c *Context) {
+		ws, err := upgradeWebSocket(c, cfg)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		defer ws.finish()
+		fn(ws)
+	}
+}
+
+// upgradeWebSocket validates the handshake, hijacks the connection and
+// sends the 101 response along with any headers set by middleware.
+func upgradeWebSocket(c *Context, cfg WSConfig) (*WSConn, error) {
+	r := c.Request
+	header := c.Response.Header()
+
+	if !hasToken(r.Header, "Connection", "upgrade") || !hasToken(r.Header, "Upgrade", "websocket") {
+		header.Set("Upgrade", "websocket")
+		return nil, NewHTTPError(http.StatusUpgradeRequired, "websocket upgrade required")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		header.Set("Sec-WebSocket-Version", "13")
+		return nil, NewHTTPError(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, NewHTTPError(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := cfg.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, NewHTTPError(http.StatusForbidden, "origin not allowed")
+	}
+	if c.Written() {
+		return nil, ErrResponseAlreadySent
+	}
+
+	subprotocol := selectSubprotocol(r.Header, cfg.Subprotocols)
+	compress := cfg.EnableCompression && acceptDeflate(r.Header)
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	buf.WriteString("Sec-WebSocket-Accept: " + wsAcceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		buf.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		buf.WriteString("Sec-WebSocket-Extensions: " + wsDeflateExtension + "\r\n")
+	}
+	for name, values := range header {
+		for _, v := range values {
+			buf.WriteString(name + ": " + v + "\r\n")
+		}
+	}
+	buf.WriteString("\r\n")
+
+	conn, brw, err := http.NewResponseController(c.Response).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	c.written = true
+
+	// Clear any deadlines set by the server's timeouts.
+	conn.SetDeadline(time.Time{})
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ws := newWSConn(conn, brw.Reader, bufio.NewWriter(conn), true, cfg)
+	ws.ctx = c
+	ws.json = c.config()
+	ws.subprotocol = subprotocol
+	ws.compress = compress
+	ws.startKeepalive()
+	return ws, nil
+}
+
+// WSConn is a WebSocket connection. One goroutine may read at a time;
+// writes are safe for concurrent use.
+type WSConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	bw          *bufio.Writer
+	server      bool
+	ctx         *Context
+	json        *Config
+	subprotocol string
+	compress    bool
+	readLimit   int64
+	frameSize   int
+	keepalive   time.Duration
+
+	msgMu     sync.Mutex // held while a data message is written
+	frameMu   sync.Mutex // held while a frame is written
+	closeSent bool
+	closeOnce sync.Once
+	stop      chan struct{}
+
+	readErr     error
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+func newWSConn(conn net.Conn, br *bufio.Reader, bw *bufio.Writer, server bool, cfg WSConfig) *WSConn {
+	ws := &WSConn{
+		conn:      conn,
+		br:        br,
+		bw:        bw,
+		server:    server,
+		json:      &DefaultConfig,
+		readLimit: cfg.ReadLimit,
+		frameSize: cfg.FrameSize,
+		keepalive: cfg.PingInterval,
+		stop:      make(chan struct{}),
+	}
+	if ws.readLimit <= 0 {
+		ws.readLimit = wsDefaultReadLimit
+	}
+	if ws.frameSize <= 0 {
+		ws.frameSize = wsDefaultFrameSize
+	}
+	ws.pingHandler = func(data []byte) error {
+		err := ws.writeFrame(true, false, WSPong, data)
+		if errors.Is(err, ErrWSClosed) {
+			return nil
+		}
+		return err
+	}
+	ws.pongHandler = func([]byte) error { return nil }
+	return ws
+}
+
+// Context returns the request context of the upgrade request.
+// It is nil for client connections.
+func (ws *WSConn) Context() *Context {
+	return ws.ctx
+}
+
+// Param retrieves a path parameter of the upgrade request by name.
+func (ws *WSConn) Param(name string) string {
+	if ws.ctx == nil {
+		return ""
+	}
+	return ws.ctx.Param(name)
+}
+
+// Subprotocol returns the negotiated subprotocol.
+func (ws *WSConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// Compressed reports whether permessage-deflate was negotiated.
+func (ws *WSConn) Compressed() bool {
+	return ws.compress
+}
+
+// RemoteAddr returns the address of the peer.
+func (ws *WSConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// SetReadLimit sets the maximum message size in bytes.
+func (ws *WSConn) SetReadLimit(limit int64) {
+	ws.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline for reads.
+func (ws *WSConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for writes.
+func (ws *WSConn) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// SetPingHandler sets the function called for each ping read by ReadMessage.
+// The default handler replies with a pong.
+func (ws *WSConn) SetPingHandler(h func(data []byte) error) {
+	ws.pingHandler = h
+}
+
+// SetPongHandler sets the function called for each pong read by ReadMessage.
+func (ws *WSConn) SetPongHandler(h func(data []byte) error) {
+	ws.pongHandler = h
+}
+
+// ReadMessage reads the next data message, reassembling fragments and
+// decompressing it if needed. Control frames are handled while reading.
+// A close frame is answered and reported as a *WSCloseError; once a read
+// fails, every later read returns the same error.
+func (ws *WSConn) ReadMessage() (messageType int, data []byte, err error) {
+	if ws.readErr != nil {
+		return 0, nil, ws.readErr
+	}
+	messageType, data, err = ws.readMessage()
+	if err != nil {
+		ws.readErr = err
+	}
+	return messageType, data, err
+}
+
+func (ws *WSConn) readMessage() (int, []byte, error) {
+	var (
+		messageType int
+		compressed  bool
+		data        []byte
+	)
+	for {
+		fin, rsv1, opcode, payload, err := ws.readFrame(ws.readLimit - int64(len(data)))
+		if err != nil {
+			return 0, nil, err
+		}
+		if ws.keepalive > 0 {
+			ws.conn.SetReadDeadline(time.Now().Add(2 * ws.keepalive))
+		}
+
+		switch opcode {
+		case WSPing:
+			if err := ws.pingHandler(payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case WSPong:
+			if err := ws.pongHandler(payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case WSClose:
+			return 0, nil, ws.handleClose(payload)
+		case wsContinuation:
+			if messageType == 0 || rsv1 {
+				return 0, nil, ws.fail(WSCloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			if messageType != 0 {
+				return 0, nil, ws.fail(WSCloseProtocolError, "expected continuation frame")
+			}
+			messageType = int(opcode)
+			compressed = rsv1
+		}
+
+		data = append(data, payload...)
+		if fin {
+			break
+		}
+	}
+
+	if compressed {
+		var err error
+		if data, err = ws.inflate(data); err != nil {
+			return 0, nil, err
+		}
+	}
+	if messageType == WSText && !utf8.Valid(data) {
+		return 0, nil, ws.fail(WSCloseInvalidPayload, "invalid UTF-8 in text message")
+	}
+	return messageType, data, nil
+}
+
+// readFrame reads a single frame, rejecting frames that violate the protocol.
+func (ws *WSConn) readFrame(limit int64) (fin, rsv1 bool, opcode byte, payload []byte, err error) {
+	var head [8]byte
+	if _, err = io.ReadFull(ws.br, head[:2]); err != nil {
+		return false, false, 0, nil, ws.readError(err)
+	}
+
+	fin = head[0]&0x80 != 0
+	rsv1 = head[0]&0x40 != 0
+	opcode = head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+
+	switch {
+	case head[0]&0x30 != 0 || (rsv1 && !ws.compress):
+		return false, false, 0, nil, ws.fail(WSCloseProtocolError, "unexpected reserved bits")
+	case opcode > WSBinary && opcode < WSClose || opcode > WSPong:
+		return false, false, 0, nil, ws.fail(WSCloseProtocolError, "unknown opcode "+strconv.Itoa(int(opcode)))
+	case masked != ws.server:
+		return false, false, 0, nil, ws.fail(WSCloseProtocolError, "incorrect frame masking")
+	case opcode >= WSClose && (!fin || rsv1 || length > wsMaxControlPayload):
+		return false, false, 0, nil, ws.fail(WSCloseProtocolError, "invalid control frame")
+	}
+
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(ws.br, head[:2]); err != nil {
+			return false, false, 0, nil, ws.readError(err)
+		}
+		length = int64(binary.BigEndian.Uint16(head[:2]))
+	case 127:
+		if _, err = io.ReadFull(ws.br, head[:8]); err != nil {
+			return false, false, 0, nil, ws.readError(err)
+		}
+		length = int64(binary.BigEndian.Uint64(head[:8]))
+		if length < 0 {
+			return false, false, 0, nil, ws.fail(WSCloseProtocolError, "invalid frame length")
+		}
+	}
+	if opcode < WSClose && length > limit {
+		return false, false, 0, nil, ws.fail(WSCloseMessageTooBig, "message exceeds read limit")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(ws.br, mask[:]); err != nil {
+			return false, false, 0, nil, ws.readError(err)
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.br, payload); err != nil {
+		return false, false, 0, nil, ws.readError(err)
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return fin, rsv1, opcode, payload, nil
+}
+
+// readError reports a dropped connection as an abnormal closure.
+func (ws *WSConn) readError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &WSCloseError{Code: WSCloseAbnormal, Text: "unexpected EOF"}
+	}
+	return err
+}
+
+// handleClose answers a close frame with the same status code.
+func (ws *WSConn) handleClose(payload []byte) error {
+	if len(payload) == 0 {
+		ws.WriteClose(WSCloseNoStatus, "")
+		return &WSCloseError{Code: WSCloseNoStatus}
+	}
+	if len(payload) == 1 {
+		return ws.fail(WSCloseProtocolError, "invalid close payload")
+	}
+
+	code := int(binary.BigEndian.Uint16(payload))
+	reason := payload[2:]
+	if !validCloseCode(code) {
+		return ws.fail(WSCloseProtocolError, "invalid close code "+strconv.Itoa(code))
+	}
+	if !utf8.Valid(reason) {
+		return ws.fail(WSCloseInvalidPayload, "invalid UTF-8 in close reason")
+	}
+	ws.WriteClose(code, "")
+	return &WSCloseError{Code: code, Text: string(reason)}
+}
+
+// fail closes the connection with the given code after a protocol violation.
+func (ws *WSConn) fail(code int, text string) error {
+	ws.WriteClose(code, text)
+	return &WSCloseError{Code: code, Text: text}
+}
+
+func validCloseCode(code int) bool {
+	return code >= 1000 && code <= 1003 || code >= 1007 && code <= 1014 || code >= 3000 && code <= 4999
+}
+
+// inflate decompresses a permessage-deflate message within the read limit.
+func (ws *WSConn) inflate(data []byte) ([]byte, error) {
+	fr := flateReaderPool.Get().(io.ReadCloser)
+	defer flateReaderPool.Put(fr)
+	fr.(flate.Resetter).Reset(io.MultiReader(bytes.NewReader(data), bytes.NewReader(wsDeflateTail)), nil)
+
+	out, err := io.ReadAll(io.LimitReader(fr, ws.readLimit+1))
+	if err != nil {
+		return nil, ws.fail(WSCloseInvalidPayload, "invalid compressed message")
+	}
+	if int64(len(out)) > ws.readLimit {
+		return nil, ws.fail(WSCloseMessageTooBig, "message exceeds read limit")
+	}
+	return out, nil
+}
+
+// WriteMessage writes a message of the given type. Text and binary
+// messages are fragmented and compressed as configured.
+func (ws *WSConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case WSText, WSBinary:
+		w, err := ws.NextWriter(messageType)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	case WSPing, WSPong:
+		if len(data) > wsMaxControlPayload {
+			return errors.New("websocket: control frame payload too large")
+		}
+		return ws.writeFrame(true, false, byte(messageType), data)
+	default:
+		return errors.New("websocket: invalid message type " + strconv.Itoa(messageType))
+	}
+}
+
+// WriteText writes a text message.
+func (ws *WSConn) WriteText(text string) error {
+	return ws.WriteMessage(WSText, []byte(text))
+}
+
+// WriteJSON writes v as a JSON text message.
+func (ws *WSConn) WriteJSON(v interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := ws.json.encodeJSON(buf, v); err != nil {
+		return err
+	}
+	return ws.WriteMessage(WSText, bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v.
+func (ws *WSConn) ReadJSON(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return ws.json.decodeJSON(bytes.NewReader(data), v)
+}
+
+// Ping sends a ping with the given payload.
+func (ws *WSConn) Ping(data []byte) error {
+	return ws.WriteMessage(WSPing, data)
+}
+
+// NextWriter returns a writer for a text or binary message. Data is sent in
+// frames of at most FrameSize bytes as it is written; Close sends the final
+// frame. Other writers block until it is closed.
+func (ws *WSConn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if messageType != WSText && messageType != WSBinary {
+		return nil, errors.New("websocket: invalid message type " + strconv.Itoa(messageType))
+	}
+	ws.msgMu.Lock()
+	w := &wsMessageWriter{ws: ws, opcode: byte(messageType), compress: ws.compress}
+	if w.compress {
+		w.fw = flateWriterPool.Get().(*flate.Writer)
+		w.fw.Reset(&w.out)
+	}
+	return w, nil
+}
+
+// Close sends a close frame with the given code and reason and closes the
+// underlying connection.
+func (ws *WSConn) Close(code int, reason string) error {
+	err := ws.WriteClose(code, reason)
+	ws.closeOnce.Do(func() {
+		close(ws.stop)
+		if cerr := ws.conn.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
+
+// finish closes the connection normally once the handler returns.
+func (ws *WSConn) finish() {
+	ws.Close(WSCloseNormal, "")
+}
+
+// WriteClose sends a close frame without closing the connection, unless one
+// was already sent. ReadMessage then returns the peer's reply as a *WSCloseError.
+func (ws *WSConn) WriteClose(code int, reason string) error {
+	var payload []byte
+	if code != WSCloseNoStatus {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+		if len(payload) > wsMaxControlPayload {
+			payload = payload[:wsMaxControlPayload]
+		}
+	}
+	err := ws.writeFrame(true, false, WSClose, payload)
+	if errors.Is(err, ErrWSClosed) {
+		return nil
+	}
+	return err
+}
+
+// writeFrame writes a single frame, masking it on client connections.
+func (ws *WSConn) writeFrame(fin, rsv1 bool, opcode byte, payload []byte) error {
+	ws.frameMu.Lock()
+	defer ws.frameMu.Unlock()
+
+	if ws.closeSent {
+		return ErrWSClosed
+	}
+	if opcode == WSClose {
+		ws.closeSent = true
+	}
+
+	var head [14]byte
+	head[0] = opcode
+	if fin {
+		head[0] |= 0x80
+	}
+	if rsv1 {
+		head[0] |= 0x40
+	}
+	n := 2
+	switch length := len(payload); {
+	case length <= wsMaxControlPayload:
+		head[1] = byte(length)
+	case length <= 0xffff:
+		head[1] = 126
+		binary.BigEndian.PutUint16(head[2:], uint16(length))
+		n = 4
+	default:
+		head[1] = 127
+		binary.BigEndian.PutUint64(head[2:], uint64(length))
+		n = 10
+	}
+
+	if !ws.server {
+		var mask [4]byte
+		rand.Read(mask[:])
+		head[1] |= 0x80
+		copy(head[n:], mask[:])
+		n += 4
+		payload = append([]byte(nil), payload...)
+		maskBytes(mask, payload)
+	}
+
+	if _, err := ws.bw.Write(head[:n]); err != nil {
+		return err
+	}
+	if _, err := ws.bw.Write(payload); err != nil {
+		return err
+	}
+	return ws.bw.Flush()
+}
+
+// startKeepalive sends pings at the configured interval until the connection closes.
+func (ws *WSConn) startKeepalive() {
+	if ws.keepalive <= 0 {
+		return
+	}
+	ws.conn.SetReadDeadline(time.Now().Add(2 * ws.keepalive))
+	go func() {
+		ticker := time.NewTicker(ws.keepalive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if ws.Ping(nil) != nil {
+					return
+				}
+			case <-ws.stop:
+				return
+			}
+		}
+	}()
+}
+
+// wsMessageWriter fragments a message into frames as it is written.
+type wsMessageWriter struct {
+	ws       *WSConn
+	opcode   byte
+	compress bool
+	fw       *flate.Writer
+	out      bytes.Buffer
+	started  bool
+	closed   bool
+}
+
+func (w *wsMessageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrWSClosed
+	}
+	if w.compress {
+		if _, err := w.fw.Write(p); err != nil {
+			return 0, err
+		}
+	} else {
+		w.out.Write(p)
+	}
+
+	// Hold back the last four bytes of compressed data, which may be the
+	// sync flush marker stripped from the final frame.
+	held := 0
+	if w.compress {
+		held = 4
+	}
+	for w.out.Len()-held > w.ws.frameSize {
+		if err := w.flushFrame(false, w.out.Next(w.ws.frameSize)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *wsMessageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.ws.msgMu.Unlock()
+
+	if w.compress {
+		err := w.fw.Flush()
+		flateWriterPool.Put(w.fw)
+		if err != nil {
+			return err
+		}
+		w.out.Truncate(len(bytes.TrimSuffix(w.out.Bytes(), wsDeflateTail[:4])))
+	}
+	return w.flushFrame(true, w.out.Bytes())
+}
+
+func (w *wsMessageWriter) flushFrame(fin bool, payload []byte) error {
+	opcode := byte(wsContinuation)
+	if !w.started {
+		opcode = w.opcode
+	}
+	err := w.ws.writeFrame(fin, w.compress && !w.started, opcode, payload)
+	w.started = true
+	return err
+}
+
+var flateWriterPool = sync.Pool{
+	New: func() interface{} {
+		fw, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return fw
+	},
+}
+
+var flateReaderPool = sync.Pool{
+	New: func() interface{} {
+		return flate.NewReader(nil)
+	},
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i&3]
+	}
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerTokens returns the comma-separated values of a header.
+func headerTokens(h http.Header, name string) []string {
+	var tokens []string
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// hasToken reports whether a header contains a token, ignoring case.
+func hasToken(h http.Header, name, token string) bool {
+	for _, t := range headerTokens(h, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether the Origin header, if any, matches the request host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// selectSubprotocol picks the first supported subprotocol offered by the client.
+func selectSubprotocol(h http.Header, supported []string) string {
+	offered := headerTokens(h, "Sec-WebSocket-Protocol")
+	for _, s := range supported {
+		for _, o := range offered {
+			if o == s {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// acceptDeflate reports whether the client offered permessage-deflate with
+// parameters the server can honour. Contexts are never taken over, and the
+// window size cannot be reduced below the 32KB used by compress/flate.
+func acceptDeflate(h http.Header) bool {
+	for _, ext := range headerTokens(h, "Sec-WebSocket-Extensions") {
+		params := strings.Split(ext, ";")
+		if strings.TrimSpace(params[0]) != "permessage-deflate" {
+			continue
+		}
+		ok := true
+		for _, p := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			switch strings.TrimSpace(name) {
+			case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+			case "server_max_window_bits":
+				ok = ok && strings.Trim(value, `" `) == "15"
+			default:
+				ok = false
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DialWebSocket opens a client connection to a WebSocket endpoint, for
+// example one served by httptest.NewServer. The URL may use the ws, wss,
+// http or https scheme. When the server refuses the upgrade, the response
+// is returned with ErrWSBadHandshake.
+func DialWebSocket(rawURL string, header http.Header, config ...WSConfig) (*WSConn, *http.Response, error) {
+	var cfg WSConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	var nonce [16]byte
+	rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if len(cfg.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(cfg.Subprotocols, ", "))
+	}
+	if cfg.EnableCompression {
+		req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate; client_no_context_takeover; server_no_context_takeover")
+	}
+
+	// The transport hands back a writable body for 101 responses.
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		return nil, res, ErrWSBadHandshake
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		res.Body.Close()
+		return nil, res, ErrWSBadHandshake
+	}
+
+	ws := newWSConn(wsClientConn{rwc}, bufio.NewReader(rwc), bufio.NewWriter(rwc), false, cfg)
+	ws.subprotocol = res.Header.Get("Sec-WebSocket-Protocol")
+	ws.compress = strings.HasPrefix(res.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+	return ws, res, nil
+}
+
+// wsClientConn adapts the upgraded body returned by http.Transport to net.Conn.
+// Deadlines are not supported on client connections.
+type wsClientConn struct {
+	io.ReadWriteCloser
+}
+
+func (wsClientConn) LocalAddr() net.Addr              { return nil }
+func (wsClientConn) RemoteAddr() net.Addr             { return nil }
+func (wsClientConn) SetDeadline(time.Time) error      { return http.ErrNotSupported }
+func (wsClientConn) SetReadDeadline(time.Time) error  { return http.ErrNotSupported }
+func (wsClientConn) SetWriteDeadline(time.Time) error { return http.ErrNotSupported }
@@ -0,0 +1,158 @@
+package zinc
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter wraps an http.ResponseWriter to record the status code,
+// the number of bytes written and whether the headers have been sent.
+// It passes flushing, hijacking, server push and io.ReaderFrom through to the
+// underlying writer, and supports http.ResponseController via Unwrap.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+	before      []func()
+}
+
+// reset points the writer at a new underlying response.
+func (w *ResponseWriter) reset(rw http.ResponseWriter) {
+	w.ResponseWriter = rw
+	w.status = 0
+	w.size = 0
+	w.wroteHeader = false
+	w.before = nil
+}
+
+// Before registers fn to run just before the headers are sent,
+// while they can still be modified.
+func (w *ResponseWriter) Before(fn func()) {
+	w.before = append(w.before, fn)
+}
+
+// WriteHeader sends the status code. Informational (1xx) responses other
+// than 101 may be sent before the final status; later calls are ignored.
+func (w *ResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if hooks := w.before; len(hooks) > 0 {
+		w.before = nil
+		for _, fn := range hooks {
+			fn()
+		}
+	}
+	w.status = code
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the body, sending a 200 status first if none was sent.
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// ReadFrom copies from r, using the underlying io.ReaderFrom when available
+// so that files can be sent with sendfile.
+func (w *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, r)
+	}
+	w.size += n
+	return n, err
+}
+
+// Flush sends any buffered data to the client.
+func (w *ResponseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError sends any buffered data to the client, returning
+// http.ErrNotSupported if the underlying writer cannot flush.
+func (w *ResponseWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection.
+// The response is considered written once the connection is hijacked.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.wroteHeader = true
+		if w.status == 0 {
+			w.status = http.StatusSwitchingProtocols
+		}
+	}
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push, returning http.ErrNotSupported
+// if the underlying writer does not support it.
+func (w *ResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status code sent, or 0 if the headers have not been sent.
+func (w *ResponseWriter) Status() int {
+	return w.status
+}
+
+// Size returns the number of body bytes written.
+func (w *ResponseWriter) Size() int64 {
+	return w.size
+}
+
+// Written reports whether the headers have been sent.
+func (w *ResponseWriter) Written() bool {
+	return w.wroteHeader
+}
+
+// writerOnly hides any io.ReaderFrom implementation to avoid recursion in io.Copy.
+type writerOnly struct {
+	io.Writer
+}
+
+// statusWriter replaces the 200 status sent by http.ServeFile and friends
+// with the status set on the context. Other statuses pass through.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if code == http.StatusOK {
+		code = w.status
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -1,60 +1,100 @@
 package zinc
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"sync"
 )
 
 type App struct {
 	router     *Router
 	middleware []Middleware
+	handlers   []Middleware
 	services   map[string]interface{}
+	container  container
 	config     *Config
+	codecs     *codecRegistry
+	cookieKeys []cookieKey
+
+	trustedProxies []netip.Prefix
+
+	serverMu sync.Mutex
+	server   *http.Server
 }
 
 type RouteHandler func(c *Context)
 
 type Map map[string]interface{}
 
-func New() *App {
+// New creates an application.
+// An optional Config overrides DefaultConfig; unset fields keep their defaults.
+// It panics if Config.TrustedProxies holds an invalid address or range.
+// With DefaultValidator, routes panic when added if the request type of a
+// typed handler has invalid `validate` tags.
+func New(config ...Config) *App {
 	cfg := DefaultConfig
-	return &App{
-		router:     &Router{},
+	if len(config) > 0 {
+		cfg = config[0]
+		cfg.setDefaults()
+	}
+	a := &App{
+		router:     &Router{checkTags: cfg.Validator == DefaultValidator},
 		middleware: make([]Middleware, 0),
 		services:   make(map[string]interface{}),
 		config:     &cfg,
+		codecs:     newCodecRegistry(&cfg),
+		cookieKeys: deriveCookieKeys(cfg.CookieKeys),
+
+		trustedProxies: parseTrustedProxies(cfg.TrustedProxies),
 	}
+	a.handlers = []Middleware{a.dispatch}
+	return a
 }
 
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := NewContext(w, r)
 	defer ctx.release()
 
+	ctx.app = a
 	ctx.services = a.services
+	ctx.bodyLimit = a.config.BodyLimit
+	ctx.wrapBody()
 
-	if len(a.middleware) > 0 {
-		ctx.setHandlers(a.middleware)
-		ctx.Next()
-		if ctx.written {
-			return
-		}
-	}
+	ctx.setHandlers(a.handlers)
+	ctx.Next()
+	ctx.writeStatus()
+}
 
-	handler, params := a.router.Find(r.Method, r.URL.Path)
-	if handler != nil {
-		ctx.PathParams = params
-		handler(ctx)
+// dispatch routes the request. It is the last handler in the application chain,
+// so application middleware wraps routing and the route handlers.
+func (a *App) dispatch(c *Context) {
+	route, params, status := a.router.find(c.Request)
+	if route != nil {
+		c.PathParams = params
+		c.route, c.logger = route.path, nil
+		route.handler(c)
+		c.writeStatus()
 		return
 	}
 
-	http.NotFound(w, r)
+	if status == http.StatusNotFound {
+		http.NotFound(c.Response, c.Request)
+		return
+	}
+	http.Error(c.Response, http.StatusText(status), status)
 }
 
 func (a *App) Use(middleware ...Middleware) {
 	a.middleware = append(a.middleware, middleware...)
+	a.handlers = append(append(make([]Middleware, 0, len(a.middleware)+1), a.middleware...), a.dispatch)
 }
 
+// Service registers a service by name, for Context.Service.
+// Provide registers services by type instead.
 func (a *App) Service(name string, service interface{}) {
 	a.services[name] = service
 }
@@ -68,8 +108,31 @@ func (a *App) Serve(port ...string) error {
 	if serverPort[0] != ':' {
 		addr = ":" + serverPort
 	}
+	if err := a.CheckServices(); err != nil {
+		return err
+	}
+
+	server := &http.Server{Addr: addr, Handler: a}
+	a.serverMu.Lock()
+	a.server = server
+	a.serverMu.Unlock()
+
 	fmt.Printf("Server starting on port %s...\n", serverPort)
-	return http.ListenAndServe(addr, a)
+	return server.ListenAndServe()
+}
+
+// Shutdown gracefully stops the server started by Serve, then closes the
+// singleton services built by the application.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.serverMu.Lock()
+	server := a.server
+	a.serverMu.Unlock()
+
+	var err error
+	if server != nil {
+		err = server.Shutdown(ctx)
+	}
+	return errors.Join(err, a.container.close())
 }
 
 func parseArgs(a *App) string {
@@ -2,12 +2,30 @@ package zinc
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestBasicRouting(t *testing.T) {
@@ -600,4 +618,2446 @@ func TestErrorHandling(t *testing.T) {
 	if response["error"] != "Internal Server Error" {
 		t.Errorf("expected error 'Internal Server Error'; got %q", response["error"])
 	}
+
+	if DefaultConfig.ErrorHandler == nil {
+		t.Error("expected DefaultConfig to set ErrorHandler")
+	}
+	if New(Config{DefaultAddr: ":0"}).config.ErrorHandler == nil {
+		t.Error("expected an unset ErrorHandler to default to DefaultErrorHandler")
+	}
+}
+
+func TestRouteMatchers(t *testing.T) {
+	app := New()
+
+	app.Get("/items", Accepts("application/vnd.acme.v2+json"), func(c *Context) {
+		c.Send("v2")
+	})
+	app.Get("/items", Header("X-API-Version", "1"), func(c *Context) {
+		c.Send("v1")
+	})
+	app.Post("/items", ContentType("application/json"), func(c *Context) {
+		c.Send("created")
+	})
+
+	app.Get("/search", HasQuery("q"), func(c *Context) {
+		c.Send("results")
+	})
+
+	v3 := app.Group("/api", HeaderRegexp("X-API-Version", `^3(\.\d+)?$`))
+	v3.Get("/items/:id", func(c *Context) {
+		c.Send("v3 " + c.Param("id"))
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Accept selects v2", "GET", "/items", map[string]string{"Accept": "application/vnd.acme.v2+json"}, 200, "v2"},
+		{"Header selects v1", "GET", "/items", map[string]string{"X-API-Version": "1"}, 200, "v1"},
+		{"No variant matches", "GET", "/items", map[string]string{"Accept": "text/html"}, 406, "Not Acceptable\n"},
+		{"Content type matches", "POST", "/items", map[string]string{"Content-Type": "application/json; charset=utf-8"}, 200, "created"},
+		{"Content type rejected", "POST", "/items", map[string]string{"Content-Type": "text/plain"}, 415, "Unsupported Media Type\n"},
+		{"Group matcher", "GET", "/api/items/7", map[string]string{"X-API-Version": "3.1"}, 200, "v3 7"},
+		{"Group matcher rejected", "GET", "/api/items/7", map[string]string{"X-API-Version": "2"}, 404, "404 page not found\n"},
+		{"Query matches", "GET", "/search?q=zinc", nil, 200, "results"},
+		{"Query missing", "GET", "/search", nil, 400, "Bad Request\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d; got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q; got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+
+	if handler, _ := app.router.Find("POST", "/items"); handler != nil {
+		t.Error("expected Find not to match a route whose matcher needs a header")
+	}
+	if handler, params := app.router.Find("GET", "/api/items/7"); handler != nil || params != nil {
+		t.Error("expected Find to apply group matchers")
+	}
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	app := New()
+	var order []string
+	mark := func(name string) Middleware {
+		return func(c *Context) { order = append(order, name) }
+	}
+	deny := func(c *Context) { c.Status(http.StatusForbidden).Send("denied") }
+
+	api := app.Group("/api")
+	api.Use(mark("api"))
+	v1 := api.Group("/v1")
+	v1.Use(mark("v1"))
+	api.Use(deny)
+	v1.Get("/secret", func(c *Context) { c.Send("secret") })
+	app.Get("/public", func(c *Context) { c.Send("public") })
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/secret", nil))
+	if w.Code != http.StatusForbidden || w.Body.String() != "denied" {
+		t.Errorf("expected middleware added to the parent after the subgroup to apply; got %d %q", w.Code, w.Body.String())
+	}
+	if strings.Join(order, ",") != "api" {
+		t.Errorf("expected the parent's middleware to run before the subgroup's; got %v", order)
+	}
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/public", nil))
+	if w.Body.String() != "public" {
+		t.Errorf("expected routes outside the group to be unaffected; got %q", w.Body.String())
+	}
+}
+
+func TestBind(t *testing.T) {
+	app := New()
+
+	type Filter struct {
+		Limit  int       `query:"limit"`
+		Tags   []string  `query:"tag"`
+		Active *bool     `query:"active"`
+		Since  time.Time `query:"since" format:"2006-01-02"`
+	}
+
+	type Request struct {
+		Filter
+		ID     int64  `path:"id"`
+		Tenant string `header:"X-Tenant"`
+		Name   string `json:"name"`
+	}
+
+	app.Put("/items/:id", func(c *Context) {
+		var req Request
+		if err := c.Bind(&req); err != nil {
+			c.Status(400).JSON(Map{"error": err.Error()})
+			return
+		}
+		c.JSON(Map{
+			"id":     req.ID,
+			"tenant": req.Tenant,
+			"name":   req.Name,
+			"limit":  req.Limit,
+			"tags":   req.Tags,
+			"active": *req.Active,
+			"since":  req.Since.Format("2006-01-02"),
+		})
+	})
+
+	app.Post("/form", func(c *Context) {
+		var form struct {
+			Name string `form:"name"`
+			Age  uint8  `form:"age"`
+		}
+		if err := c.Bind(&form); err != nil {
+			c.Status(400).JSON(err)
+			return
+		}
+		c.JSON(Map{"name": form.Name, "age": form.Age})
+	})
+
+	t.Run("Path, query, header and JSON", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/items/42?limit=10&tag=a&tag=b&active=true&since=2024-05-01", bytes.NewBufferString(`{"name":"widget"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", "acme")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		expected := `{"active":true,"id":42,"limit":10,"name":"widget","since":"2024-05-01","tags":["a","b"],"tenant":"acme"}` + "\n"
+		if w.Code != 200 || w.Body.String() != expected {
+			t.Errorf("expected 200 %q; got %d %q", expected, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Form values", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/form", bytes.NewBufferString("name=zinc&age=3"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Body.String() != `{"age":3,"name":"zinc"}`+"\n" {
+			t.Errorf("unexpected body %q", w.Body.String())
+		}
+	})
+
+	t.Run("Field errors", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/form", bytes.NewBufferString("name=zinc&age=300"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		var errs []BindError
+		if err := json.Unmarshal(w.Body.Bytes(), &errs); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if w.Code != 400 || len(errs) != 1 || errs[0].Field != "Age" || errs[0].Source != BindForm {
+			t.Errorf("unexpected errors %d %+v", w.Code, errs)
+		}
+	})
+}
+
+func TestValidation(t *testing.T) {
+	app := New()
+
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+
+	type User struct {
+		Name      string    `json:"name" validate:"required,min=2,max=10"`
+		Email     string    `json:"email" validate:"required,email"`
+		Age       int       `json:"age" validate:"gte=0,lte=130"`
+		Role      string    `json:"role" validate:"omitempty,oneof=admin user"`
+		ID        string    `json:"id" validate:"omitempty,uuid"`
+		Website   string    `json:"website" validate:"omitempty,url"`
+		Code      string    `json:"code" validate:"omitempty,regex=^[A-Z]{2,3}$"`
+		Addresses []Address `json:"addresses" validate:"max=2,dive"`
+		Tags      []string  `json:"tags" validate:"dive,len=3"`
+	}
+
+	app.Post("/users", func(c *Context) {
+		var user User
+		if err := c.Body(&user); err != nil {
+			var verrs ValidationErrors
+			if errors.As(err, &verrs) {
+				c.Status(verrs.StatusCode()).JSON(verrs)
+				return
+			}
+			c.Status(400).JSON(Map{"error": err.Error()})
+			return
+		}
+		c.Status(201).JSON(user)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		body := `{"name":"Ann","email":"ann@example.com","age":30,"role":"admin","id":"0190f7a2-3b4c-7def-8123-456789abcdef","website":"https://example.com","code":"GB","addresses":[{"city":"Leeds"}],"tags":["abc"]}`
+		req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Code != 201 {
+			t.Errorf("expected status 201; got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		body := `{"name":"A","email":"nope","age":131,"role":"root","id":"123","website":"example","code":"gb","addresses":[{"city":""}],"tags":["ab"]}`
+		req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Code != 422 {
+			t.Errorf("expected status 422; got %d", w.Code)
+		}
+
+		var errs []FieldError
+		if err := json.Unmarshal(w.Body.Bytes(), &errs); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+
+		got := map[string]string{}
+		for _, e := range errs {
+			got[e.Field] = e.Rule
+		}
+		expected := map[string]string{
+			"name":              "min",
+			"email":             "email",
+			"age":               "lte",
+			"role":              "oneof",
+			"id":                "uuid",
+			"website":           "url",
+			"code":              "regex",
+			"addresses[0].city": "required",
+			"tags[0]":           "len",
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected errors %v; got %v", expected, got)
+		}
+	})
+
+	t.Run("Custom validator", func(t *testing.T) {
+		custom := New(Config{Validator: ValidatorFunc(func(v interface{}) error {
+			return errors.New("rejected")
+		})})
+		custom.Post("/", func(c *Context) {
+			var v map[string]string
+			if err := c.Body(&v); err != nil {
+				c.Status(400).Send(err.Error())
+				return
+			}
+			c.Send("ok")
+		})
+
+		req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{}`))
+		w := httptest.NewRecorder()
+		custom.ServeHTTP(w, req)
+
+		if w.Code != 400 || w.Body.String() != "rejected" {
+			t.Errorf("expected custom validator error; got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	type BadRule struct {
+		Name string `json:"name" validate:"required,shortest"`
+	}
+	type BadParam struct {
+		Items []struct {
+			Name string `json:"name" validate:"max=ten"`
+		} `json:"items"`
+	}
+
+	t.Run("Invalid tag on typed handler", func(t *testing.T) {
+		for name, h := range map[string]*TypedHandler{
+			"rule":  Handle(func(c *Context, req BadRule) (string, error) { return "ok", nil }),
+			"param": Handle(func(c *Context, req BadParam) (string, error) { return "ok", nil }),
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("expected invalid %s to panic when the route is added", name)
+					}
+				}()
+				New().Post("/", h)
+			}()
+		}
+	})
+
+	t.Run("Invalid tag on bind", func(t *testing.T) {
+		app := New()
+		app.Post("/", func(c *Context) {
+			var v BadRule
+			if err := c.Body(&v); err != nil {
+				c.Error(err)
+				return
+			}
+			c.Send("ok")
+		})
+
+		req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"name":"ada"}`))
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Code != 500 {
+			t.Errorf("expected status 500; got %d", w.Code)
+		}
+	})
+}
+
+func TestTypedHandlers(t *testing.T) {
+	app := New()
+
+	type CreateItem struct {
+		Org  string `path:"org"`
+		Name string `json:"name" validate:"required"`
+	}
+
+	type Item struct {
+		Org  string `json:"org"`
+		Name string `json:"name"`
+	}
+
+	api := app.Group("/orgs")
+	api.Post("/:org/items", Handle(func(c *Context, req CreateItem) (Item, error) {
+		if req.Name == "taken" {
+			return Item{}, NewHTTPError(http.StatusConflict, "item already exists")
+		}
+		c.Status(http.StatusCreated)
+		return Item{Org: req.Org, Name: req.Name}, nil
+	}))
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Created", `{"name":"bolt"}`, 201, `{"org":"acme","name":"bolt"}` + "\n"},
+		{"Handler error", `{"name":"taken"}`, 409, `{"error":"item already exists"}` + "\n"},
+		{"Validation error", `{}`, 422, `{"error":"Unprocessable Entity","details":[{"field":"name","rule":"required","message":"is required"}]}` + "\n"},
+		{"Malformed body", `{`, 400, `{"error":"unexpected EOF"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/orgs/acme/items", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d; got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q; got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	app := New()
+
+	type Pet struct {
+		ID   int64    `json:"id"`
+		Name string   `json:"name" validate:"required,max=50"`
+		Tags []string `json:"tags,omitempty" validate:"dive,min=1"`
+	}
+
+	type ListPets struct {
+		Limit  int    `query:"limit" validate:"omitempty,lte=100"`
+		Tenant string `header:"X-Tenant" validate:"required"`
+	}
+
+	pets := app.Group("/pets")
+	pets.Get("/", Handle(func(c *Context, req ListPets) ([]Pet, error) {
+		return nil, nil
+	}))
+	pets.Post("/", Handle(func(c *Context, req Pet) (Pet, error) {
+		return req, nil
+	}))
+	app.Get("/pets/:id", RouteDoc{Summary: "Fetch a pet", Response: Pet{}}, func(c *Context) {})
+	app.OpenAPI(OpenAPIConfig{Title: "Pets", Version: "2.0.0"})
+
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	var doc struct {
+		OpenAPI string                                       `json:"openapi"`
+		Info    map[string]string                            `json:"info"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+		Comp    struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	if doc.OpenAPI != "3.1.0" || doc.Info["title"] != "Pets" {
+		t.Errorf("unexpected header %q %v", doc.OpenAPI, doc.Info)
+	}
+	if _, ok := doc.Paths["/openapi.json"]; ok {
+		t.Error("expected document route to be hidden")
+	}
+
+	list := doc.Paths["/pets/"]["get"]
+	if tags := list["tags"].([]interface{}); tags[0] != "/pets" {
+		t.Errorf("expected group tag; got %v", tags)
+	}
+	params, _ := json.Marshal(list["parameters"])
+	expectedParams := `[{"in":"query","name":"limit","required":false,"schema":{"format":"int64","maximum":100,"type":"integer"}},{"in":"header","name":"X-Tenant","required":true,"schema":{"type":"string"}}]`
+	if string(params) != expectedParams {
+		t.Errorf("expected parameters %s; got %s", expectedParams, params)
+	}
+
+	get := doc.Paths["/pets/{id}"]["get"]
+	if get["summary"] != "Fetch a pet" || get["operationId"] != "getPetsById" {
+		t.Errorf("unexpected operation %v", get)
+	}
+
+	pet, _ := json.Marshal(doc.Comp.Schemas["Pet"])
+	expectedPet := `{"properties":{"id":{"format":"int64","type":"integer"},"name":{"maxLength":50,"type":"string"},"tags":{"items":{"minLength":1,"type":"string"},"type":"array"}},"required":["name"],"type":"object"}`
+	if string(pet) != expectedPet {
+		t.Errorf("expected Pet schema %s; got %s", expectedPet, pet)
+	}
+
+	req = httptest.NewRequest("GET", "/docs", nil)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Code != 200 || !bytes.Contains(w.Body.Bytes(), []byte("/openapi.json")) {
+		t.Errorf("expected viewer page; got %d", w.Code)
+	}
+
+	t.Run("Routes added later", func(t *testing.T) {
+		type Search struct {
+			Query string `query:"q" validate:"required"`
+		}
+		type PetV2 struct {
+			Name string `json:"name"`
+		}
+
+		app.Get("/owners", RouteDoc{Summary: "List owners"}, func(c *Context) {})
+		app.Get("/search", HasQuery("q"), Handle(func(c *Context, req Search) ([]Pet, error) {
+			return nil, nil
+		}))
+		app.Get("/search", Header("X-Version", "2"), RouteDoc{Summary: "Search v2", Response: []PetV2{}}, func(c *Context) {})
+
+		req := httptest.NewRequest("GET", "/openapi.json", nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		var doc struct {
+			Paths map[string]map[string]map[string]interface{} `json:"paths"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("failed to parse document: %v", err)
+		}
+		if doc.Paths["/owners"]["get"]["summary"] != "List owners" {
+			t.Errorf("expected route added after the first request; got %v", doc.Paths["/owners"])
+		}
+
+		search := doc.Paths["/search"]["get"]
+		if search["summary"] != "Search v2" {
+			t.Errorf("expected summary from the second variant; got %v", search["summary"])
+		}
+		params, _ := json.Marshal(search["parameters"])
+		if expected := `[{"in":"query","name":"q","required":false,"schema":{"type":"string"}}]`; string(params) != expected {
+			t.Errorf("expected parameters %s; got %s", expected, params)
+		}
+		schema, _ := json.Marshal(search["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"])
+		if expected := `{"application/json":{"schema":{"oneOf":[{"items":{"$ref":"#/components/schemas/Pet"},"type":"array"},{"items":{"$ref":"#/components/schemas/PetV2"},"type":"array"}]}}}`; string(schema) != expected {
+			t.Errorf("expected response schema %s; got %s", expected, schema)
+		}
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	app := New()
+
+	type Row struct {
+		ID   int    `json:"id" xml:"id" yaml:"id"`
+		Name string `json:"name" xml:"name" yaml:"name"`
+	}
+
+	app.Get("/rows", func(c *Context) {
+		c.Negotiate([]Row{{1, "a"}, {2, "b, c"}})
+	})
+	app.Get("/row", func(c *Context) {
+		c.Negotiate(Row{1, "a"})
+	})
+	app.Get("/proto", func(c *Context) {
+		c.Negotiate(wrapperspb.String("zinc"))
+	})
+	app.Post("/rows", func(c *Context) {
+		var rows []Row
+		if err := c.Body(&rows); err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(rows)
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		accept       string
+		expectedCode int
+		contentType  string
+		expectedBody string
+	}{
+		{"Default JSON", "/row", "", 200, "application/json; charset=utf-8", `{"id":1,"name":"a"}` + "\n"},
+		{"XML", "/row", "application/xml", 200, "application/xml; charset=utf-8", xml.Header + `<Row><id>1</id><name>a</name></Row>`},
+		{"YAML by q-value", "/row", "application/json;q=0.5, application/yaml", 200, "application/yaml; charset=utf-8", "id: 1\nname: a\n"},
+		{"Wildcard prefers registration order", "/row", "text/*", 200, "text/xml; charset=utf-8", xml.Header + `<Row><id>1</id><name>a</name></Row>`},
+		{"CSV", "/rows", "text/csv", 200, "text/csv; charset=utf-8", "id,name\n1,a\n2,\"b, c\"\n"},
+		{"CSV unsupported for structs", "/row", "text/csv", 406, "application/json; charset=utf-8", `{"error":"Not Acceptable"}` + "\n"},
+		{"Protobuf", "/proto", "application/protobuf", 200, "application/protobuf", "\n\x04zinc"},
+		{"Nothing acceptable", "/row", "image/png", 406, "application/json; charset=utf-8", `{"error":"Not Acceptable"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("expected status %d; got %d", tt.expectedCode, w.Code)
+			}
+			if w.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("expected Content-Type %q; got %q", tt.contentType, w.Header().Get("Content-Type"))
+			}
+			if w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q; got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+
+	decoders := []struct {
+		contentType string
+		body        []byte
+	}{
+		{"application/yaml", []byte("- id: 1\n  name: a\n")},
+		{"text/csv", []byte("name,id\na,1\n")},
+		{"application/msgpack", []byte{0x91, 0x82, 0xa2, 'i', 'd', 0x01, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'a'}},
+		{"application/cbor", []byte{0x81, 0xa2, 0x62, 'i', 'd', 0x01, 0x64, 'n', 'a', 'm', 'e', 0x61, 'a'}},
+	}
+
+	for _, tt := range decoders {
+		t.Run("Decode "+tt.contentType, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/rows", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			if w.Body.String() != `[{"id":1,"name":"a"}]`+"\n" {
+				t.Errorf("unexpected body %q", w.Body.String())
+			}
+		})
+	}
+}
+
+type countingJSONCodec struct {
+	encoders, decoders int
+}
+
+func (c *countingJSONCodec) NewEncoder(w io.Writer) JSONEncoder {
+	c.encoders++
+	return json.NewEncoder(w)
+}
+
+func (c *countingJSONCodec) NewDecoder(r io.Reader) JSONDecoder {
+	c.decoders++
+	return json.NewDecoder(r)
+}
+
+func TestJSONCodec(t *testing.T) {
+	codec := &countingJSONCodec{}
+	app := New(Config{
+		JSONCodec:                 codec,
+		JSONIndent:                "  ",
+		JSONDisableHTMLEscape:     true,
+		JSONDisallowUnknownFields: true,
+	})
+
+	app.Post("/echo", func(c *Context) {
+		var v struct {
+			Tag string `json:"tag"`
+		}
+		if err := c.Body(&v); err != nil {
+			c.Status(400).Send(err.Error())
+			return
+		}
+		c.JSON(v)
+	})
+
+	req := httptest.NewRequest("POST", "/echo", bytes.NewBufferString(`{"tag":"<b>"}`))
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	expected := "{\n  \"tag\": \"<b>\"\n}\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected body %q; got %q", expected, w.Body.String())
+	}
+	if w.Header().Get("Content-Length") != strconv.Itoa(len(expected)) {
+		t.Errorf("expected Content-Length %d; got %q", len(expected), w.Header().Get("Content-Length"))
+	}
+	if codec.encoders != 1 || codec.decoders != 1 {
+		t.Errorf("expected custom codec to be used; got %d encoders, %d decoders", codec.encoders, codec.decoders)
+	}
+
+	req = httptest.NewRequest("POST", "/echo", bytes.NewBufferString(`{"tag":"a","extra":1}`))
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	if w.Code != 400 || w.Body.String() != `json: unknown field "extra"` {
+		t.Errorf("expected unknown field error; got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestResponseInspection(t *testing.T) {
+	app := New()
+
+	var status int
+	var size int64
+	var written bool
+	app.Use(func(c *Context) {
+		c.Next()
+		status, size, written = c.ResponseStatus(), c.ResponseSize(), c.Written()
+	})
+
+	app.Get("/created", func(c *Context) {
+		c.Status(201).HTML("<p>ok</p>")
+		if err := c.HTML("<p>again</p>"); err != ErrResponseAlreadySent {
+			t.Errorf("expected ErrResponseAlreadySent; got %v", err)
+		}
+	})
+	app.Get("/raw", func(c *Context) {
+		c.Response.WriteHeader(http.StatusAccepted)
+		c.Response.Write([]byte("raw"))
+		if err := c.JSON(Map{}); err != ErrResponseAlreadySent {
+			t.Errorf("expected ErrResponseAlreadySent; got %v", err)
+		}
+	}, func(c *Context) {
+		t.Error("expected chain to stop after a direct write")
+	})
+	app.Get("/static", func(c *Context) {
+		c.Status(http.StatusNonAuthoritativeInfo).Static("zinc.go")
+	})
+	app.Get("/empty", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+		expectedSize   int64
+	}{
+		{"/created", 201, 9},
+		{"/raw", 202, 3},
+		{"/missing", 404, 19},
+		{"/empty", 204, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus || status != tt.expectedStatus {
+				t.Errorf("expected status %d; got %d (recorded %d)", tt.expectedStatus, w.Code, status)
+			}
+			if size != tt.expectedSize || !written {
+				t.Errorf("expected %d bytes written; got %d (written %v)", tt.expectedSize, size, written)
+			}
+		})
+	}
+
+	t.Run("/static", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/static", nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNonAuthoritativeInfo || status != http.StatusNonAuthoritativeInfo {
+			t.Errorf("expected status 203; got %d (recorded %d)", w.Code, status)
+		}
+	})
+}
+
+func TestStreaming(t *testing.T) {
+	app := New()
+
+	app.Use(func(c *Context) {
+		c.Next()
+	})
+
+	app.Get("/stream", func(c *Context) {
+		c.Response.Header().Set("Content-Type", "text/plain")
+		n := 0
+		c.Stream(func(w io.Writer) bool {
+			n++
+			io.WriteString(w, strconv.Itoa(n)+"\n")
+			return n < 3
+		})
+	})
+	app.Get("/reader", func(c *Context) {
+		c.SendReader(strings.NewReader("a,b\n1,2\n"), "text/csv")
+	})
+	app.Get("/hijack", func(c *Context) {
+		if err := http.NewResponseController(c.Response).SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Errorf("expected write deadline to pass through; got %v", err)
+		}
+		conn, rw, err := c.Response.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("expected hijack to pass through; got %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		rw.Flush()
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/stream", nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Body.String() != "1\n2\n3\n" || !w.Flushed {
+			t.Errorf("expected flushed stream; got %q (flushed %v)", w.Body.String(), w.Flushed)
+		}
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/reader", nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Body.String() != "a,b\n1,2\n" || w.Header().Get("Content-Length") != "8" {
+			t.Errorf("unexpected response %q (Content-Length %q)", w.Body.String(), w.Header().Get("Content-Length"))
+		}
+	})
+
+	t.Run("Hijack", func(t *testing.T) {
+		srv := httptest.NewServer(app)
+		defer srv.Close()
+
+		res, err := http.Get(srv.URL + "/hijack")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		if string(body) != "hijacked" {
+			t.Errorf("expected hijacked body; got %q", body)
+		}
+	})
+}
+
+func TestSSE(t *testing.T) {
+	app := New()
+	hub := NewSSEHub(10)
+
+	app.Get("/events", func(c *Context) {
+		s, err := c.SSE()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		s.Retry(3 * time.Second)
+		s.Comment("hello\nworld")
+		s.Send("greeting", "1", "line one\nline two")
+		s.Send("", "2", Map{"n": 2})
+	})
+	app.Get("/hub", hub.Serve)
+
+	t.Run("Writer", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/events", nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Header().Get("Content-Type") != "text/event-stream" || !w.Flushed {
+			t.Errorf("unexpected headers %v (flushed %v)", w.Header(), w.Flushed)
+		}
+		expected := "retry: 3000\n\n" +
+			": hello world\n\n" +
+			"event: greeting\nid: 1\ndata: line one\ndata: line two\n\n" +
+			"id: 2\ndata: {\"n\":2}\n\n"
+		if w.Body.String() != expected {
+			t.Errorf("expected %q; got %q", expected, w.Body.String())
+		}
+	})
+
+	t.Run("Hub", func(t *testing.T) {
+		srv := httptest.NewServer(app)
+		defer srv.Close()
+
+		hub.Publish(SSEEvent{Event: "tick", Data: "a"})
+		hub.Publish(SSEEvent{Event: "tick", Data: "b"})
+
+		req, _ := http.NewRequest("GET", srv.URL+"/hub", nil)
+		req.Header.Set("Last-Event-ID", "1")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+
+		for hub.Subscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		hub.Publish(SSEEvent{Event: "tick", Data: "c"})
+
+		buf := make([]byte, 0, 128)
+		expected := "event: tick\nid: 2\ndata: b\n\nevent: tick\nid: 3\ndata: c\n\n"
+		for len(buf) < len(expected) {
+			chunk := make([]byte, 128)
+			n, err := res.Body.Read(chunk)
+			buf = append(buf, chunk[:n]...)
+			if err != nil {
+				break
+			}
+		}
+		if string(buf) != expected {
+			t.Errorf("expected %q; got %q", expected, buf)
+		}
+
+		res.Body.Close()
+		deadline := time.Now().Add(time.Second)
+		for hub.Subscribers() != 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if hub.Subscribers() != 0 {
+			t.Errorf("expected subscriber to be removed after disconnect")
+		}
+	})
+
+	t.Run("Heartbeat stops when handler returns", func(t *testing.T) {
+		var s *SSEWriter
+		app.Get("/heartbeat", func(c *Context) {
+			var err error
+			if s, err = c.SSE(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			s.Heartbeat(time.Millisecond)
+			time.Sleep(5 * time.Millisecond)
+		})
+
+		w := &lockedRecorder{ResponseRecorder: httptest.NewRecorder()}
+		app.ServeHTTP(w, httptest.NewRequest("GET", "/heartbeat", nil))
+		// Reuse the pooled Context for other requests meanwhile.
+		for i := 0; i < 5; i++ {
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/events", nil))
+		}
+
+		size := w.size()
+		time.Sleep(10 * time.Millisecond)
+		if w.size() != size {
+			t.Error("expected heartbeat to stop when the handler returned")
+		}
+		if !strings.Contains(w.body(), ": heartbeat\n\n") {
+			t.Errorf("expected heartbeats while the handler ran; got %q", w.body())
+		}
+		if err := s.Comment("late"); err == nil {
+			t.Error("expected writes after the handler returned to fail")
+		}
+	})
+}
+
+// lockedRecorder is a ResponseRecorder that can be read while written.
+type lockedRecorder struct {
+	mu sync.Mutex
+	*httptest.ResponseRecorder
+}
+
+func (r *lockedRecorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ResponseRecorder.Write(b)
+}
+
+func (r *lockedRecorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ResponseRecorder.Flush()
+}
+
+func (r *lockedRecorder) size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Body.Len()
+}
+
+func (r *lockedRecorder) body() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Body.String()
+}
+
+func TestWebSocket(t *testing.T) {
+	app := New()
+	app.Service("greeting", "hello")
+
+	auth := func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.Status(http.StatusUnauthorized).Send("unauthorized")
+		}
+	}
+
+	closed := make(chan error, 2)
+	app.WebSocket("/rooms/:room", auth, WSConfig{Subprotocols: []string{"chat"}, EnableCompression: true}, func(ws *WSConn) {
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				closed <- err
+				return
+			}
+			if messageType == WSText {
+				data = []byte(ws.Context().Service("greeting").(string) + " " + ws.Param("room") + ": " + string(data))
+			}
+			if err := ws.WriteMessage(messageType, data); err != nil {
+				closed <- err
+				return
+			}
+		}
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/rooms/general?token=secret"
+
+	t.Run("Handshake", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/rooms/general?token=secret", nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if w.Code != http.StatusUpgradeRequired {
+			t.Errorf("expected 426 for plain request; got %d", w.Code)
+		}
+
+		_, res, err := DialWebSocket(strings.TrimSuffix(url, "?token=secret"), nil)
+		if err != ErrWSBadHandshake || res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected middleware to refuse upgrade; got %v", err)
+		}
+
+		_, res, err = DialWebSocket(url, http.Header{"Origin": {"http://evil.example"}})
+		if err != ErrWSBadHandshake || res.StatusCode != http.StatusForbidden {
+			t.Errorf("expected cross-origin upgrade to be refused; got %v", err)
+		}
+	})
+
+	t.Run("Messages", func(t *testing.T) {
+		ws, _, err := DialWebSocket(url, nil, WSConfig{Subprotocols: []string{"other", "chat"}, EnableCompression: true, FrameSize: 16})
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer ws.Close(WSCloseNormal, "")
+
+		if ws.Subprotocol() != "chat" || !ws.Compressed() {
+			t.Errorf("expected chat subprotocol with compression; got %q (compressed %v)", ws.Subprotocol(), ws.Compressed())
+		}
+
+		pong := make(chan string, 1)
+		ws.SetPongHandler(func(data []byte) error {
+			pong <- string(data)
+			return nil
+		})
+		ws.Ping([]byte("ping"))
+
+		if err := ws.WriteText("hi"); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		messageType, data, err := ws.ReadMessage()
+		if err != nil || messageType != WSText || string(data) != "hello general: hi" {
+			t.Errorf("unexpected echo %d %q (%v)", messageType, data, err)
+		}
+		select {
+		case p := <-pong:
+			if p != "ping" {
+				t.Errorf("expected pong payload ping; got %q", p)
+			}
+		default:
+			t.Errorf("expected pong before echo")
+		}
+
+		large := bytes.Repeat([]byte("0123456789"), 1000)
+		ws.WriteMessage(WSBinary, large)
+		if messageType, data, err := ws.ReadMessage(); err != nil || messageType != WSBinary || !bytes.Equal(data, large) {
+			t.Errorf("expected fragmented binary echo; got %d bytes (%v)", len(data), err)
+		}
+
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		ws, _, err := DialWebSocket(url, nil)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		ws.WriteClose(4001, "bye")
+
+		_, _, err = ws.ReadMessage()
+		var ce *WSCloseError
+		if !errors.As(err, &ce) || ce.Code != 4001 {
+			t.Errorf("expected echoed close 4001; got %v", err)
+		}
+		for {
+			select {
+			case err := <-closed:
+				if errors.As(err, &ce) && ce.Code == 4001 {
+					if ce.Text != "bye" {
+						t.Errorf("expected close reason bye; got %q", ce.Text)
+					}
+					return
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected handler to see close 4001")
+			}
+		}
+	})
+}
+
+func TestCookies(t *testing.T) {
+	oldKey, newKey := []byte(strings.Repeat("o", 32)), []byte(strings.Repeat("n", 32))
+	app := New(Config{CookieKeys: [][]byte{newKey, oldKey}})
+	oldApp := New(Config{CookieKeys: [][]byte{oldKey}})
+
+	set := func(c *Context) {
+		c.SetCookie(Cookie{Name: "plain", Value: "v"})
+		c.SetCookie(Cookie{Name: "csrf", Value: "t", ScriptAccess: true, SameSite: http.SameSiteNoneMode})
+		c.ClearCookie("old")
+		c.SetSignedCookie(Cookie{Name: "signed", Value: "user:1"})
+		c.SetEncryptedCookie(Cookie{Name: "secret", Value: "user:1"})
+	}
+	get := func(c *Context) {
+		signed, serr := c.SignedCookie("signed")
+		secret, eerr := c.EncryptedCookie("secret")
+		c.JSON(Map{"plain": c.Cookie("plain"), "signed": signed, "secret": secret, "serr": fmt.Sprint(serr), "eerr": fmt.Sprint(eerr)})
+	}
+	app.Get("/set", set)
+	app.Get("/get", get)
+	oldApp.Get("/set", set)
+
+	roundTrip := func(setter *App, tamper func(*http.Cookie)) map[string]string {
+		w := httptest.NewRecorder()
+		setter.ServeHTTP(w, httptest.NewRequest("GET", "/set", nil))
+
+		req := httptest.NewRequest("GET", "/get", nil)
+		for _, cookie := range w.Result().Cookies() {
+			if tamper != nil {
+				tamper(cookie)
+			}
+			req.AddCookie(cookie)
+		}
+		w = httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		var got map[string]string
+		json.Unmarshal(w.Body.Bytes(), &got)
+		return got
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "https://example.com/set", nil)
+		app.ServeHTTP(w, req)
+
+		cookies := w.Header().Values("Set-Cookie")
+		expected := []string{
+			"plain=v; Path=/; HttpOnly; Secure; SameSite=Lax",
+			"csrf=t; Path=/; Secure; SameSite=None",
+			"old=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0; HttpOnly; Secure; SameSite=Lax",
+		}
+		for i, e := range expected {
+			if cookies[i] != e {
+				t.Errorf("expected cookie %q; got %q", e, cookies[i])
+			}
+		}
+	})
+
+	t.Run("SignedAndEncrypted", func(t *testing.T) {
+		got := roundTrip(app, nil)
+		if got["plain"] != "v" || got["signed"] != "user:1" || got["secret"] != "user:1" {
+			t.Errorf("unexpected cookies %v", got)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		got := roundTrip(oldApp, nil)
+		if got["signed"] != "user:1" || got["secret"] != "user:1" {
+			t.Errorf("expected cookies from old key to be accepted; got %v", got)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		got := roundTrip(app, func(cookie *http.Cookie) {
+			if cookie.Value != "" {
+				flipped := []byte(cookie.Value)
+				flipped[0] ^= 1
+				cookie.Value = string(flipped)
+			}
+		})
+		if got["serr"] != ErrInvalidCookie.Error() || got["eerr"] != ErrInvalidCookie.Error() {
+			t.Errorf("expected tampered cookies to be rejected; got %v", got)
+		}
+	})
+}
+
+type fakeRedis struct {
+	data map[string][]byte
+	ttls map[string]time.Duration
+}
+
+func (r *fakeRedis) Get(_ context.Context, key string) ([]byte, error) {
+	return r.data[key], nil
+}
+
+func (r *fakeRedis) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	r.data[key], r.ttls[key] = value, ttl
+	return nil
+}
+
+func (r *fakeRedis) Del(_ context.Context, key string) error {
+	delete(r.data, key)
+	return nil
+}
+
+func TestSessions(t *testing.T) {
+	fileStore, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	redis := &fakeRedis{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+
+	stores := []struct {
+		name   string
+		store  SessionStore
+		server bool
+	}{
+		{"Memory", NewMemoryStore(), true},
+		{"Cookie", CookieStore{}, false},
+		{"File", fileStore, true},
+		{"Redis", NewRedisStore(redis, "session:"), true},
+	}
+
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(Config{CookieKeys: [][]byte{[]byte(strings.Repeat("k", 32))}})
+			app.Use(Sessions(SessionConfig{Store: tt.store, TTL: time.Hour}))
+
+			app.Get("/login", func(c *Context) {
+				s := c.Session()
+				s.Set("user", "ada")
+				s.Regenerate()
+				s.Flash("notice", "welcome")
+				c.Send("ok")
+			})
+			app.Get("/me", func(c *Context) {
+				s := c.Session()
+				c.JSON(Map{"user": s.Get("user"), "notice": s.Flashes("notice")})
+			})
+			app.Get("/logout", func(c *Context) {
+				c.Session().Destroy()
+			})
+
+			do := func(path string, cookie *http.Cookie) (map[string]interface{}, *http.Cookie) {
+				req := httptest.NewRequest("GET", path, nil)
+				if cookie != nil {
+					req.AddCookie(cookie)
+				}
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, req)
+
+				var body map[string]interface{}
+				json.Unmarshal(w.Body.Bytes(), &body)
+				for _, c := range w.Result().Cookies() {
+					if c.Name == "session" {
+						return body, c
+					}
+				}
+				return body, nil
+			}
+
+			_, first := do("/login", nil)
+			if first == nil || first.MaxAge != 3600 || !first.HttpOnly {
+				t.Fatalf("expected session cookie; got %v", first)
+			}
+
+			body, second := do("/me", first)
+			if body["user"] != "ada" || fmt.Sprint(body["notice"]) != "[welcome]" || second == nil {
+				t.Errorf("expected user and flash; got %v", body)
+			}
+			if body, _ := do("/me", second); body["notice"] != nil {
+				t.Errorf("expected flash to be consumed; got %v", body["notice"])
+			}
+
+			if tt.server {
+				_, regenerated := do("/login", second)
+				if body, _ := do("/me", second); body["user"] != nil {
+					t.Errorf("expected old session to be invalid after regenerate; got %v", body)
+				}
+				second = regenerated
+			}
+
+			_, cleared := do("/logout", second)
+			if cleared == nil || cleared.MaxAge != -1 {
+				t.Errorf("expected session cookie to be cleared; got %v", cleared)
+			}
+			if body, _ := do("/me", second); tt.server && body["user"] != nil {
+				t.Errorf("expected destroyed session to be deleted; got %v", body)
+			}
+		})
+	}
+
+	t.Run("Expiry", func(t *testing.T) {
+		store := NewMemoryStore()
+		token, _ := store.Save(context.Background(), "", []byte("{}"), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		if data, _ := store.Load(context.Background(), token); data != nil || store.Len() != 0 {
+			t.Errorf("expected expired session to be evicted")
+		}
+		for key, ttl := range redis.ttls {
+			if !strings.HasPrefix(key, "session:") || ttl != time.Hour {
+				t.Errorf("expected Redis key with TTL; got %q %v", key, ttl)
+			}
+		}
+	})
+
+	t.Run("Missing cookie keys", func(t *testing.T) {
+		var handled error
+		app := New(Config{ErrorHandler: func(c *Context, err error) {
+			handled = err
+			DefaultErrorHandler(c, err)
+		}})
+		app.Use(Sessions())
+		app.Get("/", func(c *Context) {
+			t.Error("expected the handler not to run")
+		})
+
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusInternalServerError || !errors.Is(handled, ErrNoCookieKeys) {
+			t.Errorf("expected 500 wrapping ErrNoCookieKeys; got %d %v", w.Code, handled)
+		}
+	})
+
+	t.Run("Save error logged", func(t *testing.T) {
+		var logs bytes.Buffer
+		app := New(Config{CookieKeys: [][]byte{[]byte(strings.Repeat("k", 32))}})
+		app.Use(
+			Logger(LoggerConfig{Logger: slog.New(slog.NewTextHandler(&logs, nil))}),
+			Sessions(SessionConfig{Store: CookieStore{}}),
+		)
+		app.Get("/", func(c *Context) {
+			c.Session().Set("blob", strings.Repeat("x", 8192))
+			c.Send("ok")
+		})
+
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		if w.Code != 200 || len(w.Result().Cookies()) != 0 {
+			t.Errorf("expected response without session cookie; got %d %v", w.Code, w.Result().Cookies())
+		}
+		if !strings.Contains(logs.String(), "saving session") || !strings.Contains(logs.String(), ErrSessionTooLarge.Error()) {
+			t.Errorf("expected save error to be logged; got %q", logs.String())
+		}
+	})
+}
+
+func TestRedirects(t *testing.T) {
+	app := New()
+
+	app.Get("/users/:id/posts/:post", RouteName("post"), "post")
+	app.Post("/posts", func(c *Context) {
+		c.RedirectToRoute("post", map[string]string{"id": "a b", "post": "7"})
+	})
+	app.Get("/go", func(c *Context) {
+		c.Redirect(http.StatusTemporaryRedirect, "https://example.com/")
+	})
+	app.Post("/back", func(c *Context) {
+		c.RedirectBack("/home")
+	})
+	app.Redirect("/old/:id", "/new/:id", http.StatusMovedPermanently)
+	app.Redirect("/files/*", "/static/*", http.StatusPermanentRedirect)
+
+	if url, err := app.URL("post", map[string]string{"id": "1", "post": "2"}); err != nil || url != "/users/1/posts/2" {
+		t.Errorf("unexpected URL %q (%v)", url, err)
+	}
+	if _, err := app.URL("post", map[string]string{"id": "1"}); err == nil {
+		t.Errorf("expected error for missing parameter")
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		referer  string
+		status   int
+		location string
+	}{
+		{"Redirect", "GET", "/go", "", http.StatusTemporaryRedirect, "https://example.com/"},
+		{"ToRoute", "POST", "/posts", "", http.StatusSeeOther, "/users/a%20b/posts/7"},
+		{"Back", "POST", "/back", "http://example.com/form?step=2", http.StatusSeeOther, "/form?step=2"},
+		{"BackOtherHost", "POST", "/back", "http://evil.example/", http.StatusSeeOther, "/home"},
+		{"BackMissing", "POST", "/back", "", http.StatusSeeOther, "/home"},
+		{"Declarative", "GET", "/old/42?ref=x", "", http.StatusMovedPermanently, "/new/42?ref=x"},
+		{"DeclarativePost", "POST", "/old/42", "", http.StatusMovedPermanently, "/new/42"},
+		{"Wildcard", "GET", "/files/css/site.css", "", http.StatusPermanentRedirect, "/static/css/site.css"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+				t.Errorf("expected %d to %q; got %d to %q", tt.status, tt.location, w.Code, w.Header().Get("Location"))
+			}
+		})
+	}
+
+	for _, to := range []string{"/new/:slug", "/static/*"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected redirect to %q to panic on an undefined parameter", to)
+				}
+			}()
+			app.Redirect("/old-posts/:id", to, http.StatusMovedPermanently)
+		}()
+	}
+}
+
+func TestFiles(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(root, "report.txt"), []byte("0123456789"), 0o644)
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644)
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	app := New(Config{FileRoot: root})
+	app.Get("/files/*", func(c *Context) {
+		c.File(c.Param("*"))
+	})
+	app.Get("/download", func(c *Context) {
+		c.Attachment("report.txt", `Résumé "final".txt`)
+	})
+	app.Get("/inline", func(c *Context) {
+		c.Inline("report.txt", "")
+	})
+	app.Get("/escape", func(c *Context) {
+		c.File("../" + filepath.Base(outside) + "/secret.txt")
+	})
+	modtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	app.Get("/content", func(c *Context) {
+		c.ServeContent("data.csv", modtime, strings.NewReader("a,b\n1,2\n"))
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		header      http.Header
+		status      int
+		body        string
+		contentType string
+		disposition string
+	}{
+		{name: "File", path: "/files/report.txt", status: 200, body: "0123456789", contentType: "text/plain; charset=utf-8"},
+		{name: "Range", path: "/files/report.txt", header: http.Header{"Range": {"bytes=2-4"}}, status: 206, body: "234"},
+		{name: "MultiRange", path: "/files/report.txt", header: http.Header{"Range": {"bytes=0-1,8-9"}}, status: 206, contentType: "multipart/byteranges"},
+		{name: "IfRangeStale", path: "/content", header: http.Header{"Range": {"bytes=0-1"}, "If-Range": {"Sun, 31 Dec 2023 00:00:00 GMT"}}, status: 200, body: "a,b\n1,2\n"},
+		{name: "IfRangeFresh", path: "/content", header: http.Header{"Range": {"bytes=0-1"}, "If-Range": {modtime.Format(http.TimeFormat)}}, status: 206, body: "a,"},
+		{name: "Attachment", path: "/download", status: 200, disposition: `attachment; filename="R_sum_ \"final\".txt"; filename*=UTF-8''R%C3%A9sum%C3%A9%20%22final%22.txt`},
+		{name: "Inline", path: "/inline", status: 200, disposition: `inline; filename="report.txt"`},
+		{name: "Missing", path: "/files/missing.txt", status: 404},
+		{name: "Directory", path: "/files/", status: 404},
+		{name: "Escape", path: "/escape", status: 403},
+		{name: "Symlink", path: "/files/link.txt", status: 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("expected status %d; got %d", tt.status, w.Code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("expected body %q; got %q", tt.body, w.Body.String())
+			}
+			if tt.contentType != "" && !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("expected Content-Type %q; got %q", tt.contentType, w.Header().Get("Content-Type"))
+			}
+			if tt.disposition != "" && w.Header().Get("Content-Disposition") != tt.disposition {
+				t.Errorf("expected Content-Disposition %q; got %q", tt.disposition, w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
+
+func TestStaticDir(t *testing.T) {
+	modtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":          {Data: []byte("<h1>app</h1>"), ModTime: modtime},
+		"app.3f9a8c2d.js":     {Data: []byte("console.log(1)"), ModTime: modtime},
+		"app.3f9a8c2d.js.br":  {Data: []byte("brotli"), ModTime: modtime},
+		"docs/guide.txt":      {Data: []byte("guide"), ModTime: modtime},
+		"docs/naïve file.txt": {Data: []byte("naive"), ModTime: modtime},
+		".env":                {Data: []byte("SECRET=1")},
+	}
+
+	app := New()
+	app.StaticDir("/assets", fsys, StaticOptions{Browse: true, SPA: true, Precompressed: true})
+
+	do := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Index", func(t *testing.T) {
+		w := do("/assets/", nil)
+		if w.Code != 200 || w.Body.String() != "<h1>app</h1>" || w.Header().Get("Cache-Control") != "no-cache" {
+			t.Errorf("unexpected index response %d %q %v", w.Code, w.Body.String(), w.Header())
+		}
+		if w.Header().Get("ETag") == "" || w.Header().Get("Last-Modified") != modtime.Format(http.TimeFormat) {
+			t.Errorf("expected validators; got %v", w.Header())
+		}
+
+		if w := do("/assets", nil); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/assets/" {
+			t.Errorf("expected redirect to slash form; got %d %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+
+	t.Run("Precompressed", func(t *testing.T) {
+		w := do("/assets/app.3f9a8c2d.js", http.Header{"Accept-Encoding": {"gzip, br"}})
+		if w.Body.String() != "brotli" || w.Header().Get("Content-Encoding") != "br" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+			t.Errorf("expected brotli sibling; got %q %v", w.Body.String(), w.Header())
+		}
+		if w.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+			t.Errorf("expected immutable caching; got %q", w.Header().Get("Cache-Control"))
+		}
+
+		plain := do("/assets/app.3f9a8c2d.js", http.Header{"Accept-Encoding": {"gzip, br;q=0"}})
+		if plain.Body.String() != "console.log(1)" || plain.Header().Get("ETag") == w.Header().Get("ETag") {
+			t.Errorf("expected identity encoding with its own ETag; got %q %v", plain.Body.String(), plain.Header())
+		}
+	})
+
+	t.Run("NotModified", func(t *testing.T) {
+		etag := do("/assets/docs/guide.txt", nil).Header().Get("ETag")
+		if w := do("/assets/docs/guide.txt", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+			t.Errorf("expected 304; got %d", w.Code)
+		}
+	})
+
+	t.Run("Browse", func(t *testing.T) {
+		w := do("/assets/docs/", nil)
+		if !strings.Contains(w.Body.String(), `<a href="guide.txt">guide.txt</a>`) || !strings.Contains(w.Body.String(), `href="na%C3%AFve%20file.txt"`) {
+			t.Errorf("unexpected listing %q", w.Body.String())
+		}
+	})
+
+	t.Run("Hidden", func(t *testing.T) {
+		if w := do("/assets/.env", nil); w.Code != http.StatusNotFound {
+			t.Errorf("expected dotfile to be hidden; got %d", w.Code)
+		}
+	})
+
+	t.Run("SPA", func(t *testing.T) {
+		if w := do("/assets/settings/profile", http.Header{"Accept": {"text/html,*/*"}}); w.Body.String() != "<h1>app</h1>" {
+			t.Errorf("expected SPA fallback; got %d %q", w.Code, w.Body.String())
+		}
+		if w := do("/assets/missing.js", http.Header{"Accept": {"*/*"}}); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for missing asset; got %d", w.Code)
+		}
+	})
+
+	t.Run("SPA at root before routes", func(t *testing.T) {
+		app := New()
+		app.StaticDir("/", fsys, StaticOptions{SPA: true})
+		app.Get("/users/:id", func(c *Context) { c.Send("user " + c.Param("id")) })
+		app.Get("/users/:id/posts", func(c *Context) { c.Send("posts") })
+
+		tests := []struct {
+			path string
+			body string
+		}{
+			{"/users/7", "user 7"},
+			{"/users/7/posts", "posts"},
+			{"/users/7/settings", "<h1>app</h1>"},
+			{"/docs/guide.txt", "guide"},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Accept", "text/html")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			if w.Code != 200 || w.Body.String() != tt.body {
+				t.Errorf("%s: expected %q; got %d %q", tt.path, tt.body, w.Code, w.Body.String())
+			}
+		}
+	})
+
+	if !hashedName("index-BQ3x9aZk.js") || hashedName("jquery-versions.js") || hashedName("report-20240101.pdf") {
+		t.Errorf("unexpected hashed name detection")
+	}
+}
+
+func TestUploads(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+
+	multipartBody := func(files map[string][]byte) (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		mw.WriteField("title", "holiday")
+		for name, data := range files {
+			fw, _ := mw.CreateFormFile("file", name)
+			fw.Write(data)
+		}
+		mw.Close()
+		return body, mw.FormDataContentType()
+	}
+
+	do := func(app *App, path string, files map[string][]byte) *httptest.ResponseRecorder {
+		body, contentType := multipartBody(files)
+		req := httptest.NewRequest("POST", path+"?page=2", body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("MultipartForm", func(t *testing.T) {
+		app := New(Config{Uploads: UploadLimits{Memory: 1}})
+		var tempFile string
+		app.Post("/upload", func(c *Context) {
+			fh, err := c.FormFile("file")
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			f, _ := fh.Open()
+			if osFile, ok := f.(*os.File); ok {
+				tempFile = osFile.Name()
+			}
+			f.Close()
+			contentType, _ := DetectFileType(fh)
+			c.JSON(Map{"title": c.FormValue("title"), "page": c.FormValue("page"), "name": fh.Filename, "type": contentType})
+		})
+
+		w := do(app, "/upload", map[string][]byte{"photo.png": png})
+		if w.Body.String() != `{"name":"photo.png","page":"2","title":"holiday","type":"image/png"}`+"\n" {
+			t.Errorf("unexpected response %q", w.Body.String())
+		}
+		if tempFile == "" {
+			t.Fatal("expected file to spill to disk")
+		}
+		if _, err := os.Stat(tempFile); !os.IsNotExist(err) {
+			t.Errorf("expected temporary file to be removed; got %v", err)
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			limits UploadLimits
+			files  map[string][]byte
+			status int
+		}{
+			{"TotalSize", UploadLimits{MaxSize: 100}, map[string][]byte{"a.png": png}, http.StatusRequestEntityTooLarge},
+			{"FileCount", UploadLimits{MaxFiles: 1}, map[string][]byte{"a.png": png, "b.png": png}, http.StatusRequestEntityTooLarge},
+			{"FileSize", UploadLimits{MaxFileSize: 50}, map[string][]byte{"a.png": png}, http.StatusRequestEntityTooLarge},
+			{"Type", UploadLimits{AllowedTypes: []string{"image/*"}}, map[string][]byte{"a.png": []byte("plain text")}, http.StatusUnsupportedMediaType},
+			{"Allowed", UploadLimits{AllowedTypes: []string{"image/*"}, MaxFiles: 1}, map[string][]byte{"a.png": png}, http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				app := New(Config{Uploads: tt.limits})
+				app.Post("/form", func(c *Context) {
+					if _, err := c.MultipartForm(); err != nil {
+						c.Error(err)
+						return
+					}
+					c.Send("ok")
+				})
+				app.Post("/stream", func(c *Context) {
+					if err := c.Uploads(func(u *Upload) error {
+						_, err := io.Copy(io.Discard, u)
+						return err
+					}); err != nil {
+						c.Error(err)
+						return
+					}
+					c.Send("ok")
+				})
+
+				for _, path := range []string{"/form", "/stream"} {
+					if w := do(app, path, tt.files); w.Code != tt.status {
+						t.Errorf("%s: expected %d; got %d (%s)", path, tt.status, w.Code, w.Body.String())
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("Rejected form", func(t *testing.T) {
+		app := New(Config{Uploads: UploadLimits{MaxFileSize: 50}})
+		app.Post("/form", func(c *Context) {
+			_, err := c.MultipartForm()
+			if !errors.Is(err, ErrUploadTooLarge) {
+				t.Errorf("expected ErrUploadTooLarge; got %v", err)
+			}
+			if _, again := c.MultipartForm(); again != err {
+				t.Errorf("expected the same error again; got %v", again)
+			}
+			if _, err := c.FormFile("file"); err != ErrUploadTooLarge {
+				t.Errorf("expected FormFile to fail; got %v", err)
+			}
+			if v := c.FormValue("title"); v != "" {
+				t.Errorf("expected no form values; got %q", v)
+			}
+			var form struct {
+				Title string `form:"title"`
+			}
+			if err := c.Bind(&form); err != ErrUploadTooLarge || form.Title != "" {
+				t.Errorf("expected Bind to fail; got %v %q", err, form.Title)
+			}
+			if r := c.Request; r.MultipartForm != nil || r.Form != nil || r.PostForm != nil {
+				t.Error("expected the request form to be cleared")
+			}
+			c.Error(err)
+		})
+
+		if w := do(app, "/form", map[string][]byte{"a.png": png}); w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected 413; got %d", w.Code)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		app := New()
+		app.Post("/stream", func(c *Context) {
+			var files []string
+			err := c.Uploads(func(u *Upload) error {
+				n, err := io.Copy(io.Discard, u)
+				files = append(files, fmt.Sprintf("%s:%s:%s:%d", u.FieldName, u.FileName, u.ContentType, n))
+				return err
+			})
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			c.JSON(Map{"files": files, "title": c.FormValue("title"), "page": c.FormValue("page")})
+		})
+
+		w := do(app, "/stream", map[string][]byte{"photo.png": png})
+		if w.Body.String() != `{"files":["file:photo.png:image/png:108"],"page":"2","title":"holiday"}`+"\n" {
+			t.Errorf("unexpected response %q", w.Body.String())
+		}
+	})
+}
+
+func TestBodyLimits(t *testing.T) {
+	app := New(Config{BodyLimit: 64})
+	echo := func(c *Context) {
+		var v map[string]string
+		if err := c.Body(&v); err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(v)
+	}
+	app.Post("/echo", echo)
+	app.Post("/large", BodyLimit(1<<20), echo)
+	g := app.Group("/small")
+	g.Use(BodyLimit(8))
+	g.Post("/echo", echo)
+
+	compress := func(encoding string, data []byte) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "zstd":
+			w, _ = zstd.NewWriter(&buf)
+		}
+		w.Write(data)
+		w.Close()
+		return buf.Bytes()
+	}
+	small := []byte(`{"name":"zinc"}`)
+	large := []byte(`{"name":"` + strings.Repeat("z", 100) + `"}`)
+	bomb := []byte(`{"name":"` + strings.Repeat("z", 10000) + `"}`)
+
+	tests := []struct {
+		name     string
+		path     string
+		encoding string
+		body     []byte
+		status   int
+	}{
+		{"within limit", "/echo", "", small, http.StatusOK},
+		{"over limit", "/echo", "", large, http.StatusRequestEntityTooLarge},
+		{"route override", "/large", "", large, http.StatusOK},
+		{"group override", "/small/echo", "", small, http.StatusRequestEntityTooLarge},
+		{"gzip", "/echo", "gzip", compress("gzip", small), http.StatusOK},
+		{"deflate", "/echo", "deflate", compress("deflate", small), http.StatusOK},
+		{"zstd", "/echo", "zstd", compress("zstd", small), http.StatusOK},
+		{"gzip bomb", "/echo", "gzip", compress("gzip", bomb), http.StatusRequestEntityTooLarge},
+		{"zstd bomb", "/echo", "zstd", compress("zstd", bomb), http.StatusRequestEntityTooLarge},
+		{"malformed", "/echo", "gzip", small, http.StatusBadRequest},
+		{"unsupported", "/echo", "br", small, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("expected status %d; got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("Bind", func(t *testing.T) {
+		app.Post("/bind", func(c *Context) {
+			var v struct {
+				Name string `json:"name"`
+			}
+			if err := c.Bind(&v); err != nil {
+				c.Error(err)
+				return
+			}
+			c.Send(v.Name)
+		})
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest("POST", "/bind", bytes.NewReader(large)))
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status 413; got %d", w.Code)
+		}
+	})
+}
+
+func TestContextCancellation(t *testing.T) {
+	type requestKey struct{}
+	var _ context.Context = (*Context)(nil)
+
+	t.Run("Value", func(t *testing.T) {
+		app := New()
+		app.Get("/", func(c *Context) {
+			c.Set("user", "ada")
+			var ctx context.Context = c
+			c.Send(fmt.Sprint(ctx.Value("user"), " ", ctx.Value(requestKey{}), " ", ctx.Value("missing")))
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), requestKey{}, "req-1"))
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if w.Body.String() != "ada req-1 <nil>" {
+			t.Errorf("unexpected values %q", w.Body.String())
+		}
+	})
+
+	t.Run("WithTimeout", func(t *testing.T) {
+		app := New()
+		app.Get("/", func(c *Context) {
+			cancel := c.WithTimeout(time.Millisecond)
+			defer cancel()
+			if _, ok := c.Deadline(); !ok {
+				t.Error("expected a deadline")
+			}
+			select {
+			case <-c.Done():
+			case <-time.After(time.Second):
+				t.Fatal("expected context to be done")
+			}
+			c.Error(c.Err())
+		})
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500; got %d", w.Code)
+		}
+	})
+
+	t.Run("Copy", func(t *testing.T) {
+		app := New()
+		var released, copied *Context
+		app.Get("/users/:id", func(c *Context) {
+			c.Set("user", "ada")
+			released, copied = c, c.Copy()
+			c.Send("ok")
+		})
+		reqCtx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", "/users/7", nil).WithContext(reqCtx)
+		app.ServeHTTP(httptest.NewRecorder(), req)
+		cancel()
+
+		if copied.Param("id") != "7" || copied.Get("user") != "ada" {
+			t.Errorf("unexpected copy %q %v", copied.Param("id"), copied.Get("user"))
+		}
+		if copied.Err() != nil {
+			t.Errorf("expected copy not to be cancelled; got %v", copied.Err())
+		}
+		if err := copied.Send("late"); !errors.Is(err, ErrResponseAlreadySent) {
+			t.Errorf("expected ErrResponseAlreadySent; got %v", err)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("expected use after release to panic")
+			}
+		}()
+		released.Get("user")
+	})
+	t.Run("Escaped", func(t *testing.T) {
+		app := New()
+		var ctx context.Context
+		app.Get("/", func(c *Context) {
+			c.Set("user", "ada")
+			ctx = c
+			<-ctx.Done()
+		})
+		reqCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(reqCtx))
+
+		// A Context used as a context.Context stays usable by the code it was passed to.
+		if ctx.Err() != context.Canceled || ctx.Value("user") != "ada" {
+			t.Errorf("unexpected context after release: %v %v", ctx.Err(), ctx.Value("user"))
+		}
+	})
+}
+
+type greeter interface{ Greet(name string) string }
+
+type politeGreeter struct{ greeting string }
+
+func (g politeGreeter) Greet(name string) string { return g.greeting + ", " + name }
+
+func TestTypedStoreAndServices(t *testing.T) {
+	t.Run("Values", func(t *testing.T) {
+		userID := NewKey[int]("userID")
+		app := New()
+		app.Get("/", func(c *Context) {
+			if _, ok := TryValue(c, userID); ok {
+				t.Error("expected no value before it is set")
+			}
+			SetValue(c, userID, 42)
+			c.Set("name", "ada")
+			if _, ok := TryValue(c, NewKey[int]("name")); ok {
+				t.Error("expected a value of another type not to be found")
+			}
+			c.Send(strconv.Itoa(Value(c, userID)) + " " + fmt.Sprint(c.Value(userID.Name())))
+		})
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Body.String() != "42 42" {
+			t.Errorf("unexpected response %q", w.Body.String())
+		}
+	})
+
+	t.Run("Services", func(t *testing.T) {
+		app := New()
+		Provide[greeter](app, politeGreeter{greeting: "Hello"})
+		builds := 0
+		ProvideLazy(app, func() (*strings.Builder, error) {
+			builds++
+			return &strings.Builder{}, nil
+		})
+		ProvideLazy(app, func() (*bytes.Buffer, error) {
+			return nil, errors.New("no buffer")
+		})
+		app.Get("/", func(c *Context) {
+			if _, ok := TryResolve[*bytes.Buffer](c); ok {
+				t.Error("expected failed constructor not to resolve")
+			}
+			if _, ok := TryResolve[*os.File](c); ok {
+				t.Error("expected missing service not to resolve")
+			}
+			Resolve[*strings.Builder](c).WriteString("x")
+			c.Send(Resolve[greeter](c).Greet("ada"))
+		})
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			if w.Body.String() != "Hello, ada" {
+				t.Errorf("unexpected response %q", w.Body.String())
+			}
+		}
+		if builds != 1 {
+			t.Errorf("expected lazy service to be built once; got %d", builds)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("expected Resolve of a missing service to panic")
+			}
+		}()
+		Resolve[*os.File](NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)))
+	})
+}
+
+type testDB struct{ closed bool }
+
+func (db *testDB) Close() error { db.closed = true; return nil }
+
+type testTx struct {
+	db     *testDB
+	path   string
+	closed *[]string
+}
+
+func (tx *testTx) Close() { *tx.closed = append(*tx.closed, "tx "+tx.path) }
+
+type testCounter struct{ n int }
+
+type failingCloser struct{}
+
+func (failingCloser) Close() error { return errors.New("close failed") }
+
+func TestServiceLifetimes(t *testing.T) {
+	t.Run("Lifetimes", func(t *testing.T) {
+		app := New()
+		var closed []string
+		counters := 0
+		app.Register(Singleton, func() *testDB { return &testDB{} })
+		app.Register(Scoped, func(db *testDB, c *Context) *testTx {
+			return &testTx{db: db, path: c.Request.URL.Path, closed: &closed}
+		})
+		app.Register(Transient, func() *testCounter { counters++; return &testCounter{n: counters} })
+		if err := app.CheckServices(); err != nil {
+			t.Fatal(err)
+		}
+
+		var db *testDB
+		app.Get("/:n", func(c *Context) {
+			tx := Resolve[*testTx](c)
+			if Resolve[*testTx](c) != tx {
+				t.Error("expected one scoped service per request")
+			}
+			if db != nil && tx.db != db {
+				t.Error("expected one singleton")
+			}
+			db = tx.db
+			if Resolve[*testCounter](c) == Resolve[*testCounter](c) {
+				t.Error("expected a new transient service each time")
+			}
+			c.Send(tx.path)
+		})
+		for _, path := range []string{"/1", "/2"} {
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		}
+		if strings.Join(closed, ",") != "tx /1,tx /2" {
+			t.Errorf("expected scoped services closed after each request; got %v", closed)
+		}
+
+		if err := app.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if !db.closed {
+			t.Error("expected singleton closed on shutdown")
+		}
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		app := New()
+		app.Register(Singleton, func(*testTx) *testDB { return nil })
+		app.Register(Scoped, func(*testDB) *testTx { return nil })
+		app.Register(Singleton, func(*Context, *os.File) *testCounter { return nil })
+		err := app.CheckServices()
+		for _, want := range []string{
+			"singleton *zinc.testDB depends on scoped *zinc.testTx",
+			"singleton *zinc.testCounter depends on *zinc.Context",
+			"*zinc.testCounter depends on *os.File, which is not provided",
+			"cycle: *zinc.testDB -> *zinc.testTx -> *zinc.testDB",
+		} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error containing %q; got %v", want, err)
+			}
+		}
+
+		app.Get("/", func(c *Context) {
+			if _, ok := TryResolve[*testTx](c); ok {
+				t.Error("expected cyclic service not to resolve")
+			}
+			c.Send("ok")
+		})
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+
+	t.Run("Check through transient", func(t *testing.T) {
+		app := New()
+		app.Register(Scoped, func() *testTx { return nil })
+		app.Register(Transient, func(*testTx) *strings.Builder { return nil })
+		app.Register(Singleton, func(*strings.Builder) *bytes.Buffer { return nil })
+		app.Register(Transient, func(*Context) *bytes.Reader { return nil })
+		app.Register(Transient, func(*bytes.Reader) *testCounter { return nil })
+		app.Register(Singleton, func(*testCounter) *strings.Reader { return nil })
+		err := app.CheckServices()
+		for _, want := range []string{
+			"singleton *bytes.Buffer depends on scoped *zinc.testTx through transient *strings.Builder",
+			"singleton *strings.Reader depends on *zinc.Context through transient *zinc.testCounter -> *bytes.Reader",
+		} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error containing %q; got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("Close errors", func(t *testing.T) {
+		var logs bytes.Buffer
+		app := New()
+		app.Use(Logger(LoggerConfig{Logger: slog.New(slog.NewTextHandler(&logs, nil))}))
+		app.Register(Scoped, func() *failingCloser { return &failingCloser{} })
+		app.Get("/", func(c *Context) {
+			Resolve[*failingCloser](c)
+			c.Send("ok")
+		})
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		if !strings.Contains(logs.String(), "closing request services") || !strings.Contains(logs.String(), "close failed") {
+			t.Errorf("expected close error to be logged; got %q", logs.String())
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		app := New()
+		app.Register(Scoped, func() (*testTx, error) { return nil, errors.New("no connection") })
+		app.Get("/", func(c *Context) {
+			_, err := resolve[*testTx](c)
+			if err == nil || !strings.Contains(err.Error(), "no connection") {
+				t.Errorf("expected constructor error; got %v", err)
+			}
+			if _, ok := TryResolve[*testTx](c.Copy()); ok {
+				t.Error("expected scoped service not to resolve from a copy")
+			}
+			c.Send("ok")
+		})
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
+
+func TestLogger(t *testing.T) {
+	newLogger := func() (*slog.Logger, *bytes.Buffer) {
+		var buf bytes.Buffer
+		return slog.New(slog.NewJSONHandler(&buf, nil)), &buf
+	}
+	records := func(buf *bytes.Buffer) []map[string]interface{} {
+		var out []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var m map[string]interface{}
+			json.Unmarshal([]byte(line), &m)
+			out = append(out, m)
+		}
+		return out
+	}
+
+	t.Run("Record", func(t *testing.T) {
+		logger, buf := newLogger()
+		app := New()
+		app.Use(Logger(LoggerConfig{Logger: logger, SkipPaths: []string{"/health"}}), RequestID())
+		app.Get("/users/:id", func(c *Context) {
+			c.Logger().Info("loading user")
+			c.Send("ada")
+		})
+		app.Get("/health", func(c *Context) { c.Send("ok") })
+		app.Get("/fail", func(c *Context) { c.Error(errors.New("boom")) })
+
+		for _, path := range []string{"/users/7", "/health", "/fail"} {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("User-Agent", "test")
+			req.Header.Set("X-Request-ID", "req-1")
+			app.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		recs := records(buf)
+		if len(recs) != 3 {
+			t.Fatalf("expected 3 records; got %d: %s", len(recs), buf.String())
+		}
+		if recs[0]["msg"] != "loading user" || recs[0]["route"] != "/users/:id" || recs[0]["request_id"] != "req-1" {
+			t.Errorf("unexpected handler record %v", recs[0])
+		}
+		want := map[string]interface{}{
+			"level": "INFO", "msg": "request", "method": "GET", "route": "/users/:id", "path": "/users/7",
+			"status": 200.0, "bytes": 3.0, "ip": "192.0.2.1", "request_id": "req-1", "user_agent": "test",
+		}
+		for k, v := range want {
+			if recs[1][k] != v {
+				t.Errorf("expected %s=%v; got %v", k, v, recs[1][k])
+			}
+		}
+		if _, ok := recs[1]["latency"]; !ok {
+			t.Error("expected latency")
+		}
+		if recs[2]["level"] != "ERROR" || recs[2]["status"] != 500.0 {
+			t.Errorf("unexpected error record %v", recs[2])
+		}
+	})
+
+	t.Run("FieldsSkipAndSampling", func(t *testing.T) {
+		logger, buf := newLogger()
+		app := New()
+		app.Use(Logger(LoggerConfig{
+			Logger:     logger,
+			Fields:     []LogField{LogStatus},
+			SampleRate: 0.000001,
+			Skip:       func(c *Context) bool { return c.Query("quiet") != "" },
+		}))
+		app.Get("/", func(c *Context) { c.Send("ok") })
+		app.Get("/missing", func(c *Context) { c.Status(http.StatusNotFound).Send("no") })
+
+		for _, path := range []string{"/", "/missing?quiet=1", "/missing"} {
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		}
+		recs := records(buf)
+		if len(recs) != 1 {
+			t.Fatalf("expected only the unsampled error to be logged; got %s", buf.String())
+		}
+		if len(recs[0]) != 4 || recs[0]["status"] != 404.0 || recs[0]["level"] != "WARN" {
+			t.Errorf("unexpected record %v", recs[0])
+		}
+	})
+
+	t.Run("OnlyFailures", func(t *testing.T) {
+		logger, buf := newLogger()
+		app := New()
+		app.Use(Logger(LoggerConfig{Logger: logger, Fields: []LogField{LogStatus}, SampleRate: -1}))
+		app.Get("/", func(c *Context) { c.Send("ok") })
+
+		for _, path := range []string{"/", "/", "/missing"} {
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		}
+		if recs := records(buf); len(recs) != 1 || recs[0]["status"] != 404.0 {
+			t.Errorf("expected only the failure to be logged; got %s", buf.String())
+		}
+	})
+
+	t.Run("CachedLogger", func(t *testing.T) {
+		logger, buf := newLogger()
+		app := New()
+		app.Use(Logger(LoggerConfig{Logger: logger, Fields: []LogField{LogRoute}}), func(c *Context) {
+			c.Logger().Info("before routing")
+			c.Next()
+		})
+		app.Get("/users/:id", func(c *Context) {
+			if c.Logger() != c.Logger() {
+				t.Error("expected the logger to be reused within a request")
+			}
+			c.Logger().Info("routed")
+		})
+
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/7", nil))
+		recs := records(buf)
+		if len(recs) != 3 || recs[0]["route"] != nil || recs[1]["route"] != "/users/:id" {
+			t.Errorf("expected the route once routed; got %s", buf.String())
+		}
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	var outbound string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Get("X-Request-ID")
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: &RequestIDTransport{}}
+
+	app := New()
+	app.Use(RequestID())
+	app.Get("/", func(c *Context) {
+		req, _ := http.NewRequestWithContext(c, "GET", upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		resp.Body.Close()
+		c.Send(c.RequestID() + " " + RequestIDFromContext(c.Request.Context()))
+	})
+
+	t.Run("Incoming", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", "abc-123")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if w.Body.String() != "abc-123 abc-123" || w.Header().Get("X-Request-ID") != "abc-123" || outbound != "abc-123" {
+			t.Errorf("unexpected request ID: body %q, header %q, outbound %q", w.Body.String(), w.Header().Get("X-Request-ID"), outbound)
+		}
+	})
+
+	t.Run("Generated", func(t *testing.T) {
+		for _, incoming := range []string{"", "bad id", strings.Repeat("x", 129)} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Request-ID", incoming)
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			id := w.Header().Get("X-Request-ID")
+			if len(id) != 36 || id[14] != '7' || !strings.ContainsRune("89ab", rune(id[19])) {
+				t.Errorf("expected a UUIDv7 for %q; got %q", incoming, id)
+			}
+			if w.Body.String() != id+" "+id || outbound != id {
+				t.Errorf("expected generated ID %q everywhere; got body %q, outbound %q", id, w.Body.String(), outbound)
+			}
+		}
+	})
+
+	t.Run("UUIDv7Order", func(t *testing.T) {
+		a := NewUUIDv7()
+		time.Sleep(2 * time.Millisecond)
+		if b := NewUUIDv7(); b <= a {
+			t.Errorf("expected %q to sort after %q", b, a)
+		}
+	})
+}
+
+func TestClientInfo(t *testing.T) {
+	app := New(Config{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.10"}})
+	app.Get("/", func(c *Context) {
+		c.Send(c.ClientIP() + " " + c.Scheme() + " " + c.Host())
+	})
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"direct", "203.0.113.5:1234", nil, "203.0.113.5 http example.com"},
+		{"untrusted proxy", "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.test"}, "203.0.113.5 http example.com"},
+		{"trusted proxy", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "app.test"}, "198.51.100.7 https app.test"},
+		{"spoofed chain", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.7, 10.0.0.2"}, "198.51.100.7 http example.com"},
+		{"all trusted", "192.0.2.10:80", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3 http example.com"},
+		{"invalid hop", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "198.51.100.7, garbage, 10.0.0.2"}, "10.0.0.2 http example.com"},
+		{"real ip", "10.0.0.1:80", map[string]string{"X-Real-IP": "198.51.100.9"}, "198.51.100.9 http example.com"},
+		{"forwarded", "10.0.0.1:80", map[string]string{
+			"Forwarded":       `for=1.1.1.1;proto=http, for="[2001:db8::1]:4711";proto=https;host=app.test, for=10.0.0.2`,
+			"X-Forwarded-For": "9.9.9.9",
+		}, "2001:db8::1 https app.test"},
+		{"forwarded unknown", "10.0.0.1:80", map[string]string{"Forwarded": "for=unknown, for=10.0.0.2;proto=https"}, "10.0.0.2 https example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q; got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected invalid trusted proxy to panic")
+			}
+		}()
+		New(Config{TrustedProxies: []string{"not-an-ip"}})
+	})
+}
+
+func TestCORS(t *testing.T) {
+	newApp := func(cfg CORSConfig) *App {
+		app := New()
+		app.Use(CORS(cfg))
+		app.Get("/users/:id", func(c *Context) { c.Send("user") })
+		app.Put("/users/:id", func(c *Context) { c.Send("updated") })
+		app.Delete("/users/:id", func(c *Context) { c.Send("deleted") })
+		return app
+	}
+	do := func(app *App, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/users/7", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+	preflight := map[string]string{"Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "Content-Type, X-Token"}
+
+	t.Run("AnyOrigin", func(t *testing.T) {
+		app := newApp(CORSConfig{})
+		w := do(app, "GET", "https://a.test", nil)
+		if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Vary") != "" || w.Body.String() != "user" {
+			t.Errorf("unexpected response %v %q", w.Header(), w.Body.String())
+		}
+
+		w = do(app, "OPTIONS", "https://a.test", preflight)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected status 204; got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "DELETE, GET, PUT" {
+			t.Errorf("expected routed methods; got %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, X-Token" {
+			t.Errorf("expected requested headers to be allowed; got %q", got)
+		}
+	})
+
+	t.Run("Origins", func(t *testing.T) {
+		app := newApp(CORSConfig{
+			AllowOrigins:        []string{"https://app.test", "https://*.example.com"},
+			AllowOriginPatterns: []string{`https://pr-\d+\.preview\.test`},
+			AllowOriginFunc:     func(origin string) bool { return origin == "https://partner.test" },
+			AllowCredentials:    true,
+			ExposeHeaders:       []string{"X-Total"},
+		})
+		for origin, ok := range map[string]bool{
+			"https://app.test":               true,
+			"https://a.b.example.com":        true,
+			"https://example.com":            false,
+			"https://evil.test/.example.com": false,
+			"https://pr-12.preview.test":     true,
+			"https://pr-x.preview.test":      false,
+			"https://partner.test":           true,
+			"https://other.test":             false,
+		} {
+			w := do(app, "GET", origin, nil)
+			if got := w.Header().Get("Access-Control-Allow-Origin"); (got == origin) != ok {
+				t.Errorf("origin %q: expected allowed %v; got %q", origin, ok, got)
+			}
+			if ok && (w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Access-Control-Expose-Headers") != "X-Total") {
+				t.Errorf("origin %q: unexpected headers %v", origin, w.Header())
+			}
+			if w.Header().Get("Vary") != "Origin" {
+				t.Errorf("origin %q: expected Vary: Origin; got %q", origin, w.Header().Values("Vary"))
+			}
+		}
+		if w := do(app, "GET", "", nil); w.Header().Get("Vary") != "Origin" || w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("unexpected headers without Origin %v", w.Header())
+		}
+		if w := do(app, "OPTIONS", "https://other.test", preflight); w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != "" {
+			t.Errorf("expected preflight from disallowed origin to be refused; got %d %v", w.Code, w.Header())
+		}
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		app := newApp(CORSConfig{
+			AllowOrigins:        []string{"https://app.test"},
+			AllowHeaders:        []string{"Content-Type"},
+			MaxAge:              10 * time.Minute,
+			AllowPrivateNetwork: true,
+		})
+		w := do(app, "OPTIONS", "https://app.test", map[string]string{
+			"Access-Control-Request-Method":          "DELETE",
+			"Access-Control-Request-Private-Network": "true",
+		})
+		want := map[string]string{
+			"Access-Control-Allow-Origin":          "https://app.test",
+			"Access-Control-Allow-Headers":         "Content-Type",
+			"Access-Control-Max-Age":               "600",
+			"Access-Control-Allow-Private-Network": "true",
+		}
+		for k, v := range want {
+			if got := w.Header().Get(k); got != v {
+				t.Errorf("expected %s %q; got %q", k, v, got)
+			}
+		}
+		if vary := strings.Join(w.Header().Values("Vary"), ", "); vary != "Origin, Access-Control-Request-Method, Access-Control-Request-Headers, Access-Control-Request-Private-Network" {
+			t.Errorf("unexpected Vary %q", vary)
+		}
+
+		req := httptest.NewRequest("OPTIONS", "/missing", nil)
+		req.Header.Set("Origin", "https://app.test")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		w = httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected unknown path to reach the router; got %d", w.Code)
+		}
+	})
 }